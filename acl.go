@@ -0,0 +1,100 @@
+package s3
+
+import "encoding/xml"
+
+// Predefined groups that can be granted access with GrantGroup.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/acl-overview.html#specifying-grantee-predefined-groups
+const (
+	// Anyone, including anonymous requests
+	GroupAllUsers = "http://acs.amazonaws.com/groups/global/AllUsers"
+	// Any authenticated AWS account
+	GroupAuthenticatedUsers = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+	// The log delivery service writing server access logs
+	GroupLogDelivery = "http://acs.amazonaws.com/groups/s3/LogDelivery"
+)
+
+// namespace of the xsi:type attribute of a grantee
+const xmlSchemaInstance = "http://www.w3.org/2001/XMLSchema-instance"
+
+// GrantCanonicalUser grants permission to the account with the given canonical user id.
+func GrantCanonicalUser(id, permission string) Grant {
+	return newGrant("CanonicalUser", Grantee{ID: id}, permission)
+}
+
+// GrantEmail grants permission to the account registered with the given email address.
+func GrantEmail(email, permission string) Grant {
+	return newGrant("AmazonCustomerByEmail", Grantee{EmailAddress: email}, permission)
+}
+
+// GrantGroup grants permission to a predefined group, e.g. GroupAllUsers.
+func GrantGroup(uri, permission string) Grant {
+	return newGrant("Group", Grantee{URI: uri}, permission)
+}
+
+func newGrant(granteeType string, grantee Grantee, permission string) Grant {
+	grantee.XmlnsXsi = xmlSchemaInstance
+	grantee.XsiType = granteeType
+	return Grant{Grantee: grantee, Permission: permission}
+}
+
+// granteeElements are the child elements of a Grantee.
+type granteeElements struct {
+	ID           string `xml:"ID,omitempty"`
+	DisplayName  string `xml:"DisplayName,omitempty"`
+	URI          string `xml:"URI,omitempty"`
+	EmailAddress string `xml:"EmailAddress,omitempty"`
+}
+
+// MarshalXML writes the grantee with the xsi namespace declaration and its
+// xsi:type, which is derived from the set fields if XsiType is empty.
+func (g Grantee) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	granteeType := g.XsiType
+	if granteeType == "" {
+		switch {
+		case g.ID != "":
+			granteeType = "CanonicalUser"
+		case g.URI != "":
+			granteeType = "Group"
+		case g.EmailAddress != "":
+			granteeType = "AmazonCustomerByEmail"
+		}
+	}
+
+	// encoding/xml writes names without a namespace verbatim, so the
+	// prefixed attributes are emitted exactly as S3 expects them
+	start.Name = xml.Name{Local: "Grantee"}
+	start.Attr = []xml.Attr{
+		{Name: xml.Name{Local: "xmlns:xsi"}, Value: xmlSchemaInstance},
+		{Name: xml.Name{Local: "xsi:type"}, Value: granteeType},
+	}
+	return e.EncodeElement(granteeElements{
+		ID:           g.ID,
+		DisplayName:  g.DisplayName,
+		URI:          g.URI,
+		EmailAddress: g.EmailAddress,
+	}, start)
+}
+
+// UnmarshalXML reads a grantee, resolving the namespaced xsi:type attribute
+// that the struct tags can not match.
+func (g *Grantee) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var elements granteeElements
+	if err := d.DecodeElement(&elements, &start); err != nil {
+		return err
+	}
+
+	*g = Grantee{
+		XMLName:      start.Name,
+		XmlnsXsi:     xmlSchemaInstance,
+		ID:           elements.ID,
+		DisplayName:  elements.DisplayName,
+		URI:          elements.URI,
+		EmailAddress: elements.EmailAddress,
+	}
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "type" && (attr.Name.Space == xmlSchemaInstance || attr.Name.Space == "xsi") {
+			g.XsiType = attr.Value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package s3
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestGranteeMarshalXML(t *testing.T) {
+	tests := []struct {
+		name    string
+		grantee Grantee
+		xsiType string
+		want    string
+	}{
+		{
+			name:    "canonical user",
+			grantee: Grantee{ID: "79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be"},
+			xsiType: "CanonicalUser",
+			want:    `<Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="CanonicalUser"><ID>79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be</ID></Grantee>`,
+		},
+		{
+			name:    "group",
+			grantee: Grantee{URI: GroupAllUsers},
+			xsiType: "Group",
+			want:    `<Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group"><URI>http://acs.amazonaws.com/groups/global/AllUsers</URI></Grantee>`,
+		},
+		{
+			name:    "email",
+			grantee: Grantee{EmailAddress: "user@example.com"},
+			xsiType: "AmazonCustomerByEmail",
+			want:    `<Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="AmazonCustomerByEmail"><EmailAddress>user@example.com</EmailAddress></Grantee>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := xml.Marshal(tt.grantee)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got  %s\nwant %s", data, tt.want)
+			}
+
+			var decoded Grantee
+			if err := xml.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded.XsiType != tt.xsiType {
+				t.Errorf("got xsi:type %q after a round trip, want %q", decoded.XsiType, tt.xsiType)
+			}
+			if decoded.ID != tt.grantee.ID || decoded.URI != tt.grantee.URI || decoded.EmailAddress != tt.grantee.EmailAddress {
+				t.Errorf("got %+v after a round trip", decoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// newXMLDecoder returns a decoder for response documents. Unlike a plain
+// xml.Decoder it also reads documents declaring a charset other than UTF-8,
+// e.g. ISO-8859-1, as sent by some S3 compatible gateways.
+func newXMLDecoder(r io.Reader) *xml.Decoder {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charsetReader
+	return decoder
+}
+
+// unmarshalXML is xml.Unmarshal with the charset support of newXMLDecoder.
+func unmarshalXML(data []byte, v any) error {
+	return newXMLDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// charsetReader converts input in the named IANA charset to UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	// common misspelling of UTF-8, which encoding/xml handles itself
+	if strings.EqualFold(charset, "utf8") {
+		return input, nil
+	}
+	encoding, err := ianaindex.IANA.Encoding(charset)
+	if err != nil || encoding == nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return encoding.NewDecoder().Reader(input), nil
+}
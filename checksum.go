@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"strings"
+)
+
+// reversed polynomial of CRC-64/NVME as used by S3 full object checksums
+const crc64NVMEPolynomial = 0x9a6c9329ac4bc9b5
+
+var crc64NVMETable = crc64.MakeTable(crc64NVMEPolynomial)
+
+// NewCRC64NVME returns a hash computing the CRC64NVME checksum used by S3.
+func NewCRC64NVME() hash.Hash64 {
+	return crc64.New(crc64NVMETable)
+}
+
+// EncodeChecksum returns the base64 representation of a checksum hash as
+// expected in the x-amz-checksum-* headers.
+func EncodeChecksum(h hash.Hash) string {
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// ChecksumCRC64NVME returns the base64 encoded CRC64NVME checksum of data.
+func ChecksumCRC64NVME(data []byte) string {
+	var sum [8]byte
+	binary.BigEndian.PutUint64(sum[:], crc64.Checksum(data, crc64NVMETable))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// newChecksumHash returns the hash for a checksum algorithm supported by S3.
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToUpper(algorithm) {
+	case "CRC32":
+		return crc32.NewIEEE(), nil
+	case "CRC32C":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case "CRC64NVME":
+		return NewCRC64NVME(), nil
+	case "SHA1":
+		return sha1.New(), nil
+	case "SHA256":
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+}
+
+// ComputeChecksum returns the base64 encoded checksum of data for the given
+// algorithm (CRC32, CRC32C, CRC64NVME, SHA1 or SHA256).
+func ComputeChecksum(algorithm string, data []byte) (string, error) {
+	h, err := newChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	h.Write(data)
+	return EncodeChecksum(h), nil
+}
+
+// setPartChecksum stores a checksum in the field of part matching the algorithm.
+func setPartChecksum(part *CompletedPart, algorithm, value string) {
+	switch strings.ToUpper(algorithm) {
+	case "CRC32":
+		part.ChecksumCRC32 = value
+	case "CRC32C":
+		part.ChecksumCRC32C = value
+	case "CRC64NVME":
+		part.ChecksumCRC64NVME = value
+	case "SHA1":
+		part.ChecksumSHA1 = value
+	case "SHA256":
+		part.ChecksumSHA256 = value
+	}
+}
@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// checksums of "123456789", the check input of the CRC catalogue
+var checkInputChecksums = map[string]string{
+	"CRC32":     "y/Q5Jg==",
+	"CRC32C":    "4waSgw==",
+	"CRC64NVME": "rosUhgp5mIg=",
+	"SHA1":      "98O8HYCOBHMq32eZZczDTKeuNEE=",
+	"SHA256":    "FeKw08M4keuw8e9gnsQZQgwg4yDOlMZfvIwzEkSOsiU=",
+}
+
+func TestComputeChecksumKnownVectors(t *testing.T) {
+	for algorithm, want := range checkInputChecksums {
+		t.Run(algorithm, func(t *testing.T) {
+			got, err := ComputeChecksum(algorithm, []byte("123456789"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+
+	if got := ChecksumCRC64NVME([]byte("123456789")); got != checkInputChecksums["CRC64NVME"] {
+		t.Errorf("ChecksumCRC64NVME: got %s, want %s", got, checkInputChecksums["CRC64NVME"])
+	}
+	if _, err := ComputeChecksum("MD4", nil); err == nil {
+		t.Error("unsupported algorithm accepted")
+	}
+}
+
+// trailerRecorder records the body and the headers of a streamed upload.
+type trailerRecorder struct {
+	header http.Header
+	body   string
+}
+
+func (t *trailerRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	t.header = r.Header.Clone()
+	t.body = string(body)
+	w.Header().Set("ETag", `"etag"`)
+}
+
+func TestUploadPartReturnsTrailingChecksum(t *testing.T) {
+	server := &trailerRecorder{}
+	client := newTestClient(t, server)
+
+	part, err := client.UploadPart(context.Background(), "bucket", "key", strings.NewReader("123456789"), 9, 3, "upload-1", WithChecksum("CRC32C", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := CompletedPart{PartNumber: 3, ETag: "etag", ChecksumCRC32C: "4waSgw=="}
+	if *part != want {
+		t.Errorf("got part %+v, want %+v", *part, want)
+	}
+	if got := server.header.Get("x-amz-trailer"); got != "x-amz-checksum-crc32c" {
+		t.Errorf("got x-amz-trailer %q", got)
+	}
+	if !strings.HasSuffix(server.body, "x-amz-checksum-crc32c:4waSgw==\r\n\r\n") {
+		t.Errorf("body does not end with the checksum trailer: %q", server.body)
+	}
+}
+
+func TestPutObjectStreamChecksumAlgorithm(t *testing.T) {
+	server := &trailerRecorder{}
+	client := newTestClient(t, server)
+
+	data := []byte("123456789")
+	resp, err := client.PutObjectStream(context.Background(), "bucket", "key", bytes.NewReader(data), &PutObjectMetadata{
+		ContentLength:     int64(len(data)),
+		ChecksumAlgorithm: "SHA256",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := server.header.Get("x-amz-decoded-content-length"); got != "9" {
+		t.Errorf("got x-amz-decoded-content-length %q, want 9", got)
+	}
+	if want := "x-amz-checksum-sha256:" + checkInputChecksums["SHA256"] + "\r\n\r\n"; !strings.HasSuffix(server.body, want) {
+		t.Errorf("body does not end with %q: %q", want, server.body)
+	}
+}
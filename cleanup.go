@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// maximum number of keys in a single DeleteObjects request
+const maxDeleteObjects = 1000
+
+// EmptyBucket permanently deletes all object versions and delete markers of
+// a bucket and aborts all in-progress multipart uploads, so the bucket can be
+// deleted afterwards. It works on versioned and unversioned buckets.
+func (c *Client) EmptyBucket(ctx context.Context, bucketName string) error {
+	if err := c.deleteAllVersions(ctx, bucketName); err != nil {
+		return err
+	}
+	return c.abortAllMultipartUploads(ctx, bucketName)
+}
+
+// DeleteFromVersions permanently deletes the given versions and delete markers,
+// e.g. as returned by ListObjectVersions, in batches of at most 1000 keys.
+func (c *Client) DeleteFromVersions(ctx context.Context, bucketName string, versions []ObjectVersion, markers []DeleteMarkerEntry) (*DeleteResult, error) {
+	objects := make([]ObjectIdentifier, 0, len(versions)+len(markers))
+	for _, version := range versions {
+		objects = append(objects, ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+	}
+	for _, marker := range markers {
+		objects = append(objects, ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+	}
+
+	return c.DeleteObjects(ctx, bucketName, Delete{Objects: objects, Quiet: true})
+}
+
+// deleteAllVersions deletes every version and delete marker page by page.
+func (c *Client) deleteAllVersions(ctx context.Context, bucketName string) error {
+	query := make(map[string]string)
+	query["encoding-type"] = "url"
+	for {
+		page, err := c.ListObjectVersions(ctx, bucketName, query)
+		if err != nil {
+			return err
+		}
+
+		result, err := c.DeleteFromVersions(ctx, bucketName, page.Versions, page.DeleteMarkers)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, e := range result.Errors {
+			errs = append(errs, fmt.Errorf("failed to delete %s (version %s): %s: %s", e.Key, e.VersionId, e.Code, e.Message))
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+
+		if !page.IsTruncated {
+			return nil
+		}
+		query["key-marker"] = page.NextKeyMarker
+		query["version-id-marker"] = page.NextVersionIdMarker
+	}
+}
+
+// abortAllMultipartUploads aborts every in-progress multipart upload of the bucket.
+func (c *Client) abortAllMultipartUploads(ctx context.Context, bucketName string) error {
+	query := make(map[string]string)
+	query["encoding-type"] = "url"
+	for {
+		page, err := c.ListMultipartUploads(ctx, bucketName, query)
+		if err != nil {
+			return err
+		}
+
+		for _, upload := range page.Uploads {
+			if err := c.AbortMultipartUpload(ctx, bucketName, upload.Key, upload.UploadId); err != nil && !isNotFound(err) {
+				return err
+			}
+		}
+
+		if !page.IsTruncated {
+			return nil
+		}
+		query["key-marker"] = page.NextKeyMarker
+		query["upload-id-marker"] = page.NextUploadIdMarker
+	}
+}
@@ -0,0 +1,66 @@
+package s3
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ServerTime returns the current time of the service, estimated from the Date
+// header of the last response plus the local time elapsed since it arrived.
+// Before the first response it returns the local time.
+func (c *Client) ServerTime() time.Time {
+	c.clockMu.Lock()
+	defer c.clockMu.Unlock()
+
+	if c.serverDate.IsZero() {
+		return c.now().UTC()
+	}
+	// time.Now carries a monotonic reading, so local clock jumps do not matter
+	return c.serverDate.Add(c.now().Sub(c.serverDateReceived)).UTC()
+}
+
+// signingTime returns the time requests are signed with. This is the server
+// time if configured or once the service rejected a request for clock skew.
+func (c *Client) signingTime() time.Time {
+	if c.config.UseServerTime || c.skewCorrected.Load() {
+		return c.ServerTime()
+	}
+	return c.now().UTC()
+}
+
+// now returns the local time of the configured clock.
+func (c *Client) now() time.Time {
+	if c.config.Clock != nil {
+		return c.config.Clock()
+	}
+	return time.Now()
+}
+
+// recordServerTime stores the Date header of a response as the server time baseline.
+func (c *Client) recordServerTime(resp *http.Response) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	c.clockMu.Lock()
+	c.serverDate = date
+	c.serverDateReceived = c.now()
+	c.clockMu.Unlock()
+}
+
+// correctClockSkew reports whether a request was rejected because the local
+// clock is skewed and the response carries the server time. Later requests
+// are then signed with the server time.
+func (c *Client) correctClockSkew(resp *http.Response, err error) bool {
+	if resp == nil || !errors.Is(err, ErrRequestTimeTooSkewed) {
+		return false
+	}
+	// recordServerTime stored the date already
+	if _, err := http.ParseTime(resp.Header.Get("Date")); err != nil {
+		return false
+	}
+	c.skewCorrected.Store(true)
+	return true
+}
@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// adaptiveLimiter bounds the number of active transfers. The limit is halved
+// whenever the service asks to slow down and grows by one after a full window
+// of successful transfers (AIMD).
+type adaptiveLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	min       int
+	max       int
+	active    int
+	successes int
+}
+
+func newAdaptiveLimiter(initial, lower, upper int) *adaptiveLimiter {
+	lower = max(lower, 1)
+	upper = max(upper, lower)
+	l := &adaptiveLimiter{
+		limit: max(lower, min(initial, upper)),
+		min:   lower,
+		max:   upper,
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until a transfer slot is free or the context is done.
+func (l *adaptiveLimiter) acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	})
+	defer stop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l.cond.Wait()
+	}
+	l.active++
+	return nil
+}
+
+// release frees a transfer slot and adapts the limit to the transfer's outcome.
+func (l *adaptiveLimiter) release(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	switch {
+	case errors.Is(err, ErrSlowDown):
+		l.limit = max(l.min, l.limit/2)
+		l.successes = 0
+	case err == nil:
+		l.successes++
+		if l.successes >= l.limit && l.limit < l.max {
+			l.limit++
+			l.successes = 0
+		}
+	}
+	l.cond.Broadcast()
+}
@@ -0,0 +1,48 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// VerifyCredentials checks that the configured credentials and region produce
+// valid signatures for requests to a bucket, without side effects. It only
+// needs access to the bucket, not s3:ListAllMyBuckets. The returned error
+// matches ErrSignatureMismatch, ErrInvalidAccessKeyId, ErrWrongRegion,
+// ErrAccessDenied or ErrEndpointUnreachable where the cause is known.
+func (c *Client) VerifyCredentials(ctx context.Context, bucketName string) error {
+	req, err := c.newRequest(ctx, http.MethodHead, bucketName, "", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.send(req)
+	if err == nil {
+		resp.Body.Close()
+		return nil
+	}
+
+	var errorResponse ErrorResponse
+	if !errors.As(err, &errorResponse) {
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrEndpointUnreachable, err)
+	}
+
+	if region := resp.Header.Get("x-amz-bucket-region"); region != "" && region != c.bucketRegion(bucketName) {
+		return fmt.Errorf("%w: bucket %s is in region %s, requests are signed for %s", ErrWrongRegion, bucketName, region, c.bucketRegion(bucketName))
+	}
+	if errorResponse.StatusCode == http.StatusForbidden {
+		// a HEAD response has no error code, the signature is checked
+		// before permissions, so any GET reveals a signature mismatch
+		_, err := c.GetBucketLocation(ctx, bucketName)
+		if errors.Is(err, ErrSignatureMismatch) || errors.Is(err, ErrInvalidAccessKeyId) {
+			return err
+		}
+		return fmt.Errorf("%w: credentials are valid but have no access to bucket %s", ErrAccessDenied, bucketName)
+	}
+	return err
+}
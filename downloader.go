@@ -0,0 +1,471 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// number of times a part is retried after the service asked to slow down
+const maxSlowDownRetries = 5
+
+// suffix of the sidecar file tracking the parts of a partial download
+const manifestSuffix = ".s3download"
+
+// DownloadOptions contains the available options for configuring a Downloader.
+type DownloadOptions struct {
+	// Size of a single ranged GET request in bytes
+	PartSize int64
+	// Number of parts fetched in parallel at the start of a download
+	Concurrency int
+	// Lower bound the concurrency is reduced to when the service responds with SlowDown
+	MinConcurrency int
+	// Upper bound the concurrency ramps up to after sustained successes
+	MaxConcurrency int
+	// Resume continues a previous partial download of the same object
+	// instead of starting from zero.
+	Resume bool
+	// AlignToParts downloads objects uploaded in parts with the size of their
+	// first part instead of PartSize, so the ranges match the original parts.
+	AlignToParts bool
+}
+
+// Downloader fetches objects in byte ranges and writes them to a local file.
+type Downloader struct {
+	client  *Client
+	options DownloadOptions
+}
+
+// downloadManifest records which parts of an object are already on disk.
+type downloadManifest struct {
+	ETag     string  `json:"etag"`
+	Size     int64   `json:"size"`
+	PartSize int64   `json:"partSize"`
+	Parts    []int64 `json:"parts"`
+}
+
+// NewDownloader creates a new Downloader.
+func NewDownloader(client *Client, opts *DownloadOptions) *Downloader {
+	d := &Downloader{client: client}
+	if opts != nil {
+		d.options = *opts
+	}
+	if d.options.PartSize <= 0 {
+		d.options.PartSize = client.partSize()
+	}
+	if d.options.Concurrency <= 0 {
+		d.options.Concurrency = 1
+	}
+	if d.options.MinConcurrency <= 0 {
+		d.options.MinConcurrency = 1
+	}
+	if d.options.MaxConcurrency < d.options.Concurrency {
+		d.options.MaxConcurrency = d.options.Concurrency
+	}
+	return d
+}
+
+// DownloadFile downloads an object into the file at path and returns the
+// stats of the transfer, also if it failed. With Resume enabled, parts
+// already present in the file are not fetched again.
+func (d *Downloader) DownloadFile(ctx context.Context, bucketName, objectName, path string) (TransferStats, error) {
+	ctx, counter := startTransfer(ctx)
+	size, err := d.downloadFile(ctx, bucketName, objectName, path)
+	return counter.stats(size), err
+}
+
+// downloadFile downloads an object into the file at path and returns the
+// object size, or 0 if it is not known yet.
+func (d *Downloader) downloadFile(ctx context.Context, bucketName, objectName, path string) (int64, error) {
+
+	resp, err := d.client.HeadObject(ctx, bucketName, objectName)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	size := resp.ContentLength
+	if size < 0 {
+		return 0, fmt.Errorf("failed to determine object size")
+	}
+
+	manifest := downloadManifest{
+		ETag:     trimETag(resp.Header.Get("ETag")),
+		Size:     size,
+		PartSize: d.options.PartSize,
+	}
+	if d.options.AlignToParts {
+		partSize, err := d.firstPartSize(ctx, bucketName, objectName)
+		if err != nil {
+			return size, err
+		}
+		if partSize > 0 {
+			manifest.PartSize = partSize
+		}
+	}
+	manifestPath := path + manifestSuffix
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if !d.options.Resume {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return size, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	done := make(map[int64]bool)
+	if d.options.Resume {
+		done, err = d.resumeState(f, manifestPath, &manifest)
+		if err != nil {
+			return size, err
+		}
+	}
+
+	partCount := (size + manifest.PartSize - 1) / manifest.PartSize
+	var missing []int64
+	for i := int64(0); i < partCount; i++ {
+		if !done[i] {
+			missing = append(missing, i)
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := writeManifest(manifestPath, &manifest); err != nil {
+			return size, err
+		}
+		if err := d.downloadParts(ctx, bucketName, objectName, f, missing, manifestPath, &manifest); err != nil {
+			return size, err
+		}
+	}
+
+	if err := f.Truncate(size); err != nil {
+		return size, fmt.Errorf("failed to truncate file: %w", err)
+	}
+	if err := os.Remove(manifestPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return size, fmt.Errorf("failed to remove download manifest: %w", err)
+	}
+
+	return size, nil
+}
+
+// firstPartSize returns the size of the first part of an object uploaded in
+// parts, or 0 if the object consists of a single part.
+func (d *Downloader) firstPartSize(ctx context.Context, bucketName, objectName string) (int64, error) {
+	info, err := d.client.HeadObjectInfo(ctx, bucketName, objectName, WithPartNumber(1))
+	if err != nil {
+		return 0, err
+	}
+	if info.PartsCount <= 1 {
+		return 0, nil
+	}
+	return info.ContentLength, nil
+}
+
+// resumeState determines which parts of the object are already present in f.
+// A sidecar manifest is preferred; without one the existing file size is
+// treated as a contiguous prefix of the object.
+func (d *Downloader) resumeState(f *os.File, manifestPath string, current *downloadManifest) (map[int64]bool, error) {
+	done := make(map[int64]bool)
+
+	data, err := os.ReadFile(manifestPath)
+	if err == nil {
+		var previous downloadManifest
+		if err := json.Unmarshal(data, &previous); err != nil {
+			return nil, fmt.Errorf("failed to parse download manifest: %w", err)
+		}
+		// manifests of earlier versions stored the quoted ETag
+		if trimETag(previous.ETag) != current.ETag || previous.Size != current.Size || previous.PartSize <= 0 {
+			// the object changed since the last attempt
+			return done, f.Truncate(0)
+		}
+		current.PartSize = previous.PartSize
+		for _, part := range previous.Parts {
+			done[part] = true
+		}
+		current.Parts = previous.Parts
+		return done, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read download manifest: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.Size() > current.Size {
+		return done, f.Truncate(0)
+	}
+	for i := int64(0); (i+1)*current.PartSize <= info.Size(); i++ {
+		done[i] = true
+		current.Parts = append(current.Parts, i)
+	}
+
+	return done, nil
+}
+
+// downloadParts fetches the given parts with adaptive concurrency and records
+// each finished part in the manifest.
+func (d *Downloader) downloadParts(ctx context.Context, bucketName, objectName string, f *os.File, parts []int64, manifestPath string, manifest *downloadManifest) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	queue := make(chan int64)
+	limiter := newAdaptiveLimiter(d.options.Concurrency, d.options.MinConcurrency, d.options.MaxConcurrency)
+
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	for w := 0; w < d.options.MaxConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range queue {
+				if err := d.downloadPartThrottled(ctx, limiter, bucketName, objectName, f, part, manifest); err != nil {
+					fail(err)
+					continue
+				}
+
+				mu.Lock()
+				manifest.Parts = append(manifest.Parts, part)
+				err := writeManifest(manifestPath, manifest)
+				mu.Unlock()
+				if err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	for _, part := range parts {
+		select {
+		case queue <- part:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// downloadPartThrottled fetches a part within the limits of the limiter and
+// retries it when the service asks to slow down.
+func (d *Downloader) downloadPartThrottled(ctx context.Context, limiter *adaptiveLimiter, bucketName, objectName string, f *os.File, part int64, manifest *downloadManifest) error {
+	for attempt := 1; ; attempt++ {
+		if err := limiter.acquire(ctx); err != nil {
+			return err
+		}
+		err := d.downloadPart(ctx, bucketName, objectName, f, part, manifest)
+		limiter.release(err)
+
+		if !errors.Is(err, ErrSlowDown) || attempt > maxSlowDownRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+		}
+		countRetry(ctx)
+	}
+}
+
+// downloadPart fetches a single part and writes it at its offset in f.
+func (d *Downloader) downloadPart(ctx context.Context, bucketName, objectName string, f *os.File, part int64, manifest *downloadManifest) error {
+	start := part * manifest.PartSize
+	end := min(start+manifest.PartSize, manifest.Size) - 1
+
+	// a concurrent overwrite fails the part instead of mixing two versions
+	resp, err := d.client.getObjectRange(ctx, bucketName, objectName, Range{Start: start, End: end}, WithIfMatch(manifest.ETag))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkContentRange(resp, start, end, manifest.Size); err != nil {
+		return fmt.Errorf("part %d: %w", part, err)
+	}
+
+	n, err := io.Copy(io.NewOffsetWriter(f, start), resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write part %d: %w", part, err)
+	}
+	if n != end-start+1 {
+		return fmt.Errorf("received %d bytes for part %d, expected %d", n, part, end-start+1)
+	}
+	countTransferred(ctx, n, 1)
+
+	return nil
+}
+
+// checkContentRange verifies that a response holds exactly the requested
+// byte range, so misplaced bytes are never written to the file.
+func checkContentRange(resp *http.Response, start, end, size int64) error {
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("unexpected status %d for range request, expected 206", resp.StatusCode)
+	}
+	expected := fmt.Sprintf("bytes %d-%d/%d", start, end, size)
+	if contentRange := resp.Header.Get("Content-Range"); contentRange != expected {
+		return fmt.Errorf("unexpected Content-Range %q, expected %q", contentRange, expected)
+	}
+	return nil
+}
+
+func writeManifest(path string, manifest *downloadManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write download manifest: %w", err)
+	}
+	return nil
+}
+
+// DownloadToWriter copies an object into w with sequential ranged GETs of
+// PartSize bytes, so memory use stays bounded for large objects. With a
+// Concurrency above one, that many ranges are fetched in parallel and
+// buffered until they can be written in order. All ranges are requested
+// with the ETag of the first HEAD request, so a concurrent overwrite fails
+// the download with ErrPreconditionFailed instead of mixing two versions.
+// It returns the stats of the transfer, also if it failed; their Bytes are
+// the number of bytes written.
+func (c *Client) DownloadToWriter(ctx context.Context, bucketName, objectName string, w io.Writer, opts *DownloadOptions) (TransferStats, error) {
+	ctx, counter := startTransfer(ctx)
+	size, err := NewDownloader(c, opts).downloadToWriter(ctx, bucketName, objectName, w)
+	return counter.stats(size), err
+}
+
+// downloadToWriter copies an object into w and returns the object size, or 0
+// if it is not known yet.
+func (d *Downloader) downloadToWriter(ctx context.Context, bucketName, objectName string, w io.Writer) (int64, error) {
+	resp, err := d.client.HeadObject(ctx, bucketName, objectName)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	size := resp.ContentLength
+	if size < 0 {
+		return 0, fmt.Errorf("failed to determine object size")
+	}
+	if size == 0 {
+		return 0, nil
+	}
+	etag := trimETag(resp.Header.Get("ETag"))
+
+	if d.options.Concurrency <= 1 {
+		return size, d.copyRanges(ctx, bucketName, objectName, w, size, etag)
+	}
+	return size, d.copyRangesParallel(ctx, bucketName, objectName, w, size, etag)
+}
+
+// copyRanges streams the ranges of an object into w one after another.
+func (d *Downloader) copyRanges(ctx context.Context, bucketName, objectName string, w io.Writer, size int64, etag string) error {
+	for start := int64(0); start < size; start += d.options.PartSize {
+		end := min(start+d.options.PartSize, size) - 1
+		n, err := d.copyRange(ctx, bucketName, objectName, w, start, end, size, etag)
+		if err != nil {
+			countTransferred(ctx, n, 0)
+			return err
+		}
+		countTransferred(ctx, n, 1)
+	}
+	return nil
+}
+
+// copyRange fetches a single range of an object and copies it into w.
+func (d *Downloader) copyRange(ctx context.Context, bucketName, objectName string, w io.Writer, start, end, size int64, etag string) (int64, error) {
+	resp, err := d.client.getObjectRange(ctx, bucketName, objectName, Range{Start: start, End: end}, WithIfMatch(etag))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkContentRange(resp, start, end, size); err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to copy range %d-%d: %w", start, end, err)
+	}
+	if n != end-start+1 {
+		return n, fmt.Errorf("received %d bytes for range %d-%d, expected %d", n, start, end, end-start+1)
+	}
+	return n, nil
+}
+
+// rangeResult is a fetched range of an object or the error fetching it.
+type rangeResult struct {
+	data []byte
+	err  error
+}
+
+// copyRangesParallel fetches up to Concurrency ranges ahead of the writer and
+// writes them into w in order.
+func (d *Downloader) copyRangesParallel(ctx context.Context, bucketName, objectName string, w io.Writer, size int64, etag string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// every pending range is queued in order, the queue plus the range the
+	// writer waits for bounds the number of buffered ranges to Concurrency
+	pending := make(chan chan rangeResult, d.options.Concurrency-1)
+	go func() {
+		defer close(pending)
+		for start := int64(0); start < size; start += d.options.PartSize {
+			end := min(start+d.options.PartSize, size) - 1
+			result := make(chan rangeResult, 1)
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				var buf bytes.Buffer
+				buf.Grow(int(end - start + 1))
+				_, err := d.copyRange(ctx, bucketName, objectName, &buf, start, end, size, etag)
+				result <- rangeResult{data: buf.Bytes(), err: err}
+			}()
+		}
+	}()
+
+	for result := range pending {
+		r := <-result
+		if r.err != nil {
+			return r.err
+		}
+		n, err := w.Write(r.data)
+		if err != nil {
+			countTransferred(ctx, int64(n), 0)
+			return fmt.Errorf("failed to write range: %w", err)
+		}
+		countTransferred(ctx, int64(n), 1)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,119 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// objectServer serves a single object with HEAD and ranged GET requests and
+// fails ranges whose If-Match does not match its current ETag.
+type objectServer struct {
+	mu      sync.Mutex
+	data    []byte
+	etag    string
+	ifMatch []string
+}
+
+func (s *objectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("ETag", `"`+s.etag+`"`)
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.data)))
+		return
+	}
+
+	s.ifMatch = append(s.ifMatch, r.Header.Get("If-Match"))
+	if r.Header.Get("If-Match") != `"`+s.etag+`"` {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+		return
+	}
+	var start, end int
+	fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(s.data)))
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(s.data[start : end+1])
+}
+
+func TestDownloadFileSendsIfMatchWithParts(t *testing.T) {
+	server := &objectServer{data: bytes.Repeat([]byte("0123456789"), 3), etag: "v1"}
+	client := newTestClient(t, server)
+	path := filepath.Join(t.TempDir(), "object")
+
+	stats, err := NewDownloader(client, &DownloadOptions{PartSize: 10}).DownloadFile(context.Background(), "bucket", "key", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, server.data) {
+		t.Errorf("got file %q, want %q", got, server.data)
+	}
+	for i, ifMatch := range server.ifMatch {
+		if ifMatch != `"v1"` {
+			t.Errorf("range %d: got If-Match %q, want %q", i, ifMatch, `"v1"`)
+		}
+	}
+	if stats.Bytes != 30 || stats.Size != 30 || stats.Parts != 3 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+func TestDownloadFileFailsOnConcurrentOverwrite(t *testing.T) {
+	server := &objectServer{data: bytes.Repeat([]byte("0123456789"), 3), etag: "v1"}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r)
+		// the object is overwritten after the HEAD request
+		server.mu.Lock()
+		server.etag = "v2"
+		server.mu.Unlock()
+	}))
+	path := filepath.Join(t.TempDir(), "object")
+
+	stats, err := NewDownloader(client, &DownloadOptions{PartSize: 10}).DownloadFile(context.Background(), "bucket", "key", path)
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("got error %v, want ErrPreconditionFailed", err)
+	}
+	if stats.Size != 30 || stats.Parts != 0 {
+		t.Errorf("got stats %+v, want size 30 and no parts", stats)
+	}
+}
+
+func TestDownloadToWriter(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		t.Run(fmt.Sprintf("concurrency=%d", concurrency), func(t *testing.T) {
+			server := &objectServer{data: bytes.Repeat([]byte("0123456789"), 5), etag: "v1"}
+			client := newTestClient(t, server)
+
+			var buf bytes.Buffer
+			stats, err := client.DownloadToWriter(context.Background(), "bucket", "key", &buf, &DownloadOptions{PartSize: 8, Concurrency: concurrency})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), server.data) {
+				t.Errorf("got %q, want %q", buf.Bytes(), server.data)
+			}
+			if stats.Bytes != 50 || stats.Size != 50 || stats.Parts != 7 {
+				t.Errorf("got stats %+v, want 50 bytes in 7 parts", stats)
+			}
+			for i, ifMatch := range server.ifMatch {
+				if ifMatch != `"v1"` {
+					t.Errorf("range %d: got If-Match %q", i, ifMatch)
+				}
+			}
+		})
+	}
+}
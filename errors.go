@@ -0,0 +1,186 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors matched by errors.Is against an ErrorResponse with the
+// corresponding S3 error code.
+var (
+	// The request rate is too high, back off and retry
+	ErrSlowDown = errors.New("slow down")
+	// The service is temporarily unavailable
+	ErrServiceUnavailable = errors.New("service unavailable")
+	// The connection was idle for too long
+	ErrRequestTimeout = errors.New("request timeout")
+	// The write offset of an append does not match the current object size
+	ErrWriteOffsetConflict = errors.New("write offset does not match object size")
+	// The object was not modified since the time or ETag of a conditional request
+	ErrNotModified = errors.New("not modified")
+	// The object key does not exist
+	ErrNoSuchKey = errors.New("no such key")
+	// The bucket does not exist
+	ErrNoSuchBucket = errors.New("no such bucket")
+	// The object version does not exist
+	ErrNoSuchVersion = errors.New("no such version")
+	// The multipart upload does not exist, it was aborted or completed
+	ErrNoSuchUpload = errors.New("no such upload")
+	// The credentials are not allowed to perform the request
+	ErrAccessDenied = errors.New("access denied")
+	// The bucket name is taken by another account
+	ErrBucketAlreadyExists = errors.New("bucket already exists")
+	// The bucket was already created by the same account
+	ErrBucketAlreadyOwnedByYou = errors.New("bucket already owned by you")
+	// The bucket still contains objects and can not be deleted
+	ErrBucketNotEmpty = errors.New("bucket not empty")
+	// The requested range can not be satisfied
+	ErrInvalidRange = errors.New("invalid range")
+	// The bucket has no default encryption configured
+	ErrNoDefaultEncryption = errors.New("no default encryption")
+	// The signature does not match, usually because of a wrong secret key
+	ErrSignatureMismatch = errors.New("signature does not match")
+	// The access key does not exist
+	ErrInvalidAccessKeyId = errors.New("invalid access key id")
+	// The bucket must be addressed in a different region
+	ErrWrongRegion = errors.New("wrong region")
+	// The request time differs too much from the server time
+	ErrRequestTimeTooSkewed = errors.New("request time too skewed")
+)
+
+// ErrEndpointUnreachable is matched by errors.Is when VerifyCredentials got
+// no response from the endpoint.
+var ErrEndpointUnreachable = errors.New("endpoint unreachable")
+
+// ErrNotFound is matched by errors.Is for every 404 response. HEAD responses
+// carry no error document, so they can not be matched with ErrNoSuchKey or
+// ErrNoSuchBucket.
+var ErrNotFound = errors.New("not found")
+
+// errorCodes maps S3 error codes to their sentinel errors.
+var errorCodes = map[string]error{
+	"SlowDown":                ErrSlowDown,
+	"ServiceUnavailable":      ErrServiceUnavailable,
+	"RequestTimeout":          ErrRequestTimeout,
+	"InvalidWriteOffset":      ErrWriteOffsetConflict,
+	"NotModified":             ErrNotModified,
+	"NoSuchKey":               ErrNoSuchKey,
+	"NoSuchBucket":            ErrNoSuchBucket,
+	"NoSuchVersion":           ErrNoSuchVersion,
+	"NoSuchUpload":            ErrNoSuchUpload,
+	"AccessDenied":            ErrAccessDenied,
+	"Forbidden":               ErrAccessDenied,
+	"BucketAlreadyExists":     ErrBucketAlreadyExists,
+	"BucketAlreadyOwnedByYou": ErrBucketAlreadyOwnedByYou,
+	"BucketNotEmpty":          ErrBucketNotEmpty,
+	"InvalidRange":            ErrInvalidRange,
+	"SignatureDoesNotMatch":   ErrSignatureMismatch,
+	"InvalidAccessKeyId":      ErrInvalidAccessKeyId,
+	"PermanentRedirect":       ErrWrongRegion,
+	"MovedPermanently":        ErrWrongRegion,
+	"RequestTimeTooSkewed":    ErrRequestTimeTooSkewed,
+	"ServerSideEncryptionConfigurationNotFoundError": ErrNoDefaultEncryption,
+}
+
+// Is reports whether target is the sentinel error for the response's code,
+// or ErrNotFound for a 404 response.
+func (e ErrorResponse) Is(target error) bool {
+	if target == ErrNotFound {
+		return e.StatusCode == http.StatusNotFound
+	}
+	sentinel, ok := errorCodes[e.Code]
+	return ok && sentinel == target
+}
+
+// ErrPreconditionFailed is matched by errors.Is when a conditional request
+// was rejected with 412 Precondition Failed.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// PreconditionFailedError is returned when a conditional operation fails.
+// It carries the current state of the object where the server provides it,
+// so the caller can retry against the new ETag.
+type PreconditionFailedError struct {
+	ErrorResponse
+	// ETag of the object as currently stored, if returned by the server
+	CurrentETag string
+	// Last modification time of the stored object, if returned by the server
+	LastModified time.Time
+}
+
+func (e *PreconditionFailedError) Error() string {
+	if e.CurrentETag != "" {
+		return fmt.Sprintf("%s: %s (current etag %s)", e.Code, e.Message, e.CurrentETag)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Is reports whether target is ErrPreconditionFailed.
+func (e *PreconditionFailedError) Is(target error) bool {
+	return target == ErrPreconditionFailed
+}
+
+// Unwrap returns the underlying error response, so its code, request id and
+// status code remain accessible with errors.As.
+func (e *PreconditionFailedError) Unwrap() error {
+	return e.ErrorResponse
+}
+
+// newPreconditionFailedError builds a PreconditionFailedError from a 412 response.
+func newPreconditionFailedError(resp *http.Response, errorResponse ErrorResponse) *PreconditionFailedError {
+	e := &PreconditionFailedError{
+		ErrorResponse: errorResponse,
+		CurrentETag:   trimETag(resp.Header.Get("ETag")),
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		e.LastModified = lastModified
+	}
+	return e
+}
+
+// ErrDeleteMarker is matched by errors.Is when the requested object or
+// version is a delete marker, i.e. the object was deleted in a versioned bucket.
+var ErrDeleteMarker = errors.New("object is a delete marker")
+
+// DeleteMarkerError is returned when a request hits a delete marker.
+type DeleteMarkerError struct {
+	ErrorResponse
+	// Version id of the delete marker
+	VersionId string
+}
+
+func (e *DeleteMarkerError) Error() string {
+	return fmt.Sprintf("%s: %s (delete marker %s)", e.Code, e.Message, e.VersionId)
+}
+
+// Is reports whether target is ErrDeleteMarker.
+func (e *DeleteMarkerError) Is(target error) bool {
+	return target == ErrDeleteMarker
+}
+
+// Unwrap returns the underlying error response, so a delete marker is still
+// recognized as a missing object.
+func (e *DeleteMarkerError) Unwrap() error {
+	return e.ErrorResponse
+}
+
+// ListError is returned when a page of a paginated listing failed. The
+// listing can be resumed at the failed page with ContinuationToken.
+type ListError struct {
+	// Continuation token of the failed page, empty for the first page
+	ContinuationToken string
+	Err               error
+}
+
+func (e *ListError) Error() string {
+	if e.ContinuationToken == "" {
+		return fmt.Sprintf("failed to list objects: %v", e.Err)
+	}
+	return fmt.Sprintf("failed to list objects at continuation token %s: %v", e.ContinuationToken, e.Err)
+}
+
+// Unwrap returns the error of the failed request.
+func (e *ListError) Unwrap() error {
+	return e.Err
+}
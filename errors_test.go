@@ -0,0 +1,82 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestErrorResponseMatchesSentinels(t *testing.T) {
+	tests := []struct {
+		status int
+		code   string
+		want   error
+	}{
+		{http.StatusNotFound, "NoSuchKey", ErrNoSuchKey},
+		{http.StatusNotFound, "NoSuchBucket", ErrNoSuchBucket},
+		{http.StatusForbidden, "AccessDenied", ErrAccessDenied},
+		{http.StatusConflict, "BucketNotEmpty", ErrBucketNotEmpty},
+		{http.StatusServiceUnavailable, "SlowDown", ErrSlowDown},
+		{http.StatusForbidden, "SignatureDoesNotMatch", ErrSignatureMismatch},
+		{http.StatusMovedPermanently, "PermanentRedirect", ErrWrongRegion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `<Error><Code>%s</Code><Message>message</Message></Error>`, tt.code)
+			}))
+
+			_, err := client.GetObject(context.Background(), "bucket", "key")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+			var errorResponse ErrorResponse
+			if !errors.As(err, &errorResponse) || errorResponse.Code != tt.code || errorResponse.StatusCode != tt.status {
+				t.Errorf("got error %#v, want an ErrorResponse with code %s and status %d", err, tt.code, tt.status)
+			}
+			if errors.Is(err, ErrServiceUnavailable) {
+				t.Errorf("%s matches an unrelated sentinel", tt.code)
+			}
+		})
+	}
+}
+
+func TestHeadNotFoundMatchesErrNotFound(t *testing.T) {
+	client := newTestClient(t, http.NotFoundHandler())
+
+	_, err := client.HeadObject(context.Background(), "bucket", "key")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+	if errors.Is(err, ErrNoSuchBucket) {
+		t.Error("a HEAD 404 without error code matches ErrNoSuchBucket")
+	}
+}
+
+func TestPreconditionFailedErrorUnwrapsErrorResponse(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"current"`)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message><RequestId>request-1</RequestId></Error>`)
+	}))
+
+	_, err := client.GetObject(context.Background(), "bucket", "key")
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("got error %v, want ErrPreconditionFailed", err)
+	}
+	var errorResponse ErrorResponse
+	if !errors.As(err, &errorResponse) {
+		t.Fatalf("got error %#v, want an ErrorResponse", err)
+	}
+	if errorResponse.Code != "PreconditionFailed" || errorResponse.RequestID != "request-1" || errorResponse.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("got error response %+v", errorResponse)
+	}
+	var preconditionFailed *PreconditionFailedError
+	if !errors.As(err, &preconditionFailed) || preconditionFailed.CurrentETag != "current" {
+		t.Errorf("got error %#v, want a PreconditionFailedError with the current ETag", err)
+	}
+}
@@ -3,3 +3,5 @@ module github.com/cedweber/go-s3-wrapper
 go 1.23.3
 
 toolchain go1.23.6
+
+require golang.org/x/text v0.28.0
@@ -0,0 +1,232 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"net/url"
+	"sync"
+)
+
+// ObjectsSeq returns an iterator over all objects below prefix. Pages of
+// ListObjectsV2 are fetched lazily while the caller ranges over the sequence.
+// A failed request is yielded as error and ends the iteration.
+func (c *Client) ObjectsSeq(ctx context.Context, bucketName, prefix string) iter.Seq2[ObjectInfo, error] {
+	query := make(map[string]string)
+	if prefix != "" {
+		query["prefix"] = prefix
+	}
+	return c.listObjectsSeq(ctx, bucketName, query)
+}
+
+// ListAllObjects returns all objects matched by the ListObjectsV2 query,
+// following continuation tokens until all pages are read. If a page fails,
+// the objects of the previous pages are returned together with a *ListError,
+// so the returned slice is partial whenever err != nil. The listing can be
+// resumed by setting "continuation-token" to the error's ContinuationToken.
+func (c *Client) ListAllObjects(ctx context.Context, bucketName string, query map[string]string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	for object, err := range c.listObjectsSeq(ctx, bucketName, query) {
+		if err != nil {
+			return objects, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// EachObject calls fn for every object matched by the ListObjectsV2 query,
+// following continuation tokens until all pages are read. An error returned
+// by fn stops the listing and is returned.
+func (c *Client) EachObject(ctx context.Context, bucketName string, query map[string]string, fn func(ObjectInfo) error) error {
+	for object, err := range c.listObjectsSeq(ctx, bucketName, query) {
+		if err != nil {
+			return err
+		}
+		if err := fn(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrefixSize returns the total size and the number of all objects below
+// prefix, including those in nested prefixes. Objects are summed page by
+// page, so memory use does not grow with the number of objects.
+func (c *Client) PrefixSize(ctx context.Context, bucketName, prefix string) (int64, int64, error) {
+	var totalBytes, objectCount int64
+	for object, err := range c.ObjectsSeq(ctx, bucketName, prefix) {
+		if err != nil {
+			return 0, 0, err
+		}
+		totalBytes += int64(object.Size)
+		objectCount++
+	}
+	return totalBytes, objectCount, nil
+}
+
+// ListAllBucketsObjects lists the objects below prefix in all given buckets,
+// with up to concurrency buckets listed in parallel. The concurrency is
+// reduced while the service asks to slow down and grows back after sustained
+// successes, but never above concurrency. For buckets that failed the
+// objects listed up to the failure are returned, their errors are joined into
+// the returned error.
+func (c *Client) ListAllBucketsObjects(ctx context.Context, buckets []string, prefix string, concurrency int) (map[string][]ObjectInfo, error) {
+	concurrency = max(concurrency, 1)
+	limiter := newAdaptiveLimiter(concurrency, 1, concurrency)
+	query := make(map[string]string)
+	if prefix != "" {
+		query["prefix"] = prefix
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string][]ObjectInfo, len(buckets))
+		errs    []error
+	)
+	for _, bucketName := range buckets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := limiter.acquire(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("bucket %s: %w", bucketName, err))
+				mu.Unlock()
+				return
+			}
+			objects, err := c.ListAllObjects(ctx, bucketName, query)
+			limiter.release(err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("bucket %s: %w", bucketName, err))
+			}
+			if err == nil || len(objects) > 0 {
+				results[bucketName] = objects
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// listObjectsSeq pages through ListObjectsV2 with a copy of the given query.
+func (c *Client) listObjectsSeq(ctx context.Context, bucketName string, query map[string]string) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		query := maps.Clone(query)
+		if query == nil {
+			query = make(map[string]string)
+		}
+		if _, ok := query["encoding-type"]; !ok {
+			// keys with control characters can only be listed URL encoded
+			query["encoding-type"] = "url"
+		}
+
+		for {
+			page, err := c.ListObjectsV2(ctx, bucketName, query)
+			if err != nil {
+				yield(ObjectInfo{}, &ListError{ContinuationToken: query["continuation-token"], Err: err})
+				return
+			}
+
+			for _, object := range page.Contents {
+				if !yield(object, nil) {
+					return
+				}
+			}
+
+			if !page.IsTruncated || page.NextContinuationToken == "" {
+				return
+			}
+			query["continuation-token"] = page.NextContinuationToken
+		}
+	}
+}
+
+// urlDecoder decodes the fields of a listing returned with encoding-type=url
+// and keeps the first error.
+type urlDecoder struct {
+	err error
+}
+
+func (d *urlDecoder) decode(value *string) {
+	if d.err != nil || *value == "" {
+		return
+	}
+	decoded, err := url.QueryUnescape(*value)
+	if err != nil {
+		d.err = fmt.Errorf("failed to decode %q: %w", *value, err)
+		return
+	}
+	*value = decoded
+}
+
+// decodeURLEncoding decodes the keys and prefixes of a URL encoded listing.
+func (r *ListObjectsResponse) decodeURLEncoding() error {
+	if r.EncodingType != "url" {
+		return nil
+	}
+	var d urlDecoder
+	for _, value := range []*string{&r.Delimiter, &r.Marker, &r.NextMarker, &r.Prefix, &r.StartAfter} {
+		d.decode(value)
+	}
+	for i := range r.Contents {
+		d.decode(&r.Contents[i].Key)
+	}
+	for i := range r.CommonPrefixes {
+		d.decode(&r.CommonPrefixes[i].Prefix)
+	}
+	return d.err
+}
+
+// decodeURLEncoding decodes the keys and prefixes of a URL encoded listing.
+func (r *ListVersionsResult) decodeURLEncoding() error {
+	if r.EncodingType != "url" {
+		return nil
+	}
+	var d urlDecoder
+	for _, value := range []*string{&r.Delimiter, &r.KeyMarker, &r.NextKeyMarker, &r.Prefix} {
+		d.decode(value)
+	}
+	for i := range r.Versions {
+		d.decode(&r.Versions[i].Key)
+	}
+	for i := range r.DeleteMarkers {
+		d.decode(&r.DeleteMarkers[i].Key)
+	}
+	for i := range r.CommonPrefixes {
+		d.decode(&r.CommonPrefixes[i].Prefix)
+	}
+	return d.err
+}
+
+// decodeURLEncoding decodes the keys and prefixes of a URL encoded listing.
+func (r *ListMultipartUploadsResult) decodeURLEncoding() error {
+	if r.EncodingType != "url" {
+		return nil
+	}
+	var d urlDecoder
+	for _, value := range []*string{&r.Delimiter, &r.KeyMarker, &r.NextKeyMarker, &r.Prefix} {
+		d.decode(value)
+	}
+	for i := range r.Uploads {
+		d.decode(&r.Uploads[i].Key)
+	}
+	for i := range r.CommonPrefixes {
+		d.decode(&r.CommonPrefixes[i].Prefix)
+	}
+	return d.err
+}
+
+// trimETags removes the quotes around the ETags of the listed objects.
+func (r *ListObjectsResponse) trimETags() {
+	for i := range r.Contents {
+		r.Contents[i].ETag = trimETag(r.Contents[i].ETag)
+	}
+}
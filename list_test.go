@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// pagedListing serves ListObjectsV2 pages of two keys each and records the
+// continuation tokens of the requests.
+type pagedListing struct {
+	keys   []string
+	tokens []string
+}
+
+func (l *pagedListing) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("list-type") != "2" {
+		http.Error(w, "not a ListObjectsV2 request", http.StatusBadRequest)
+		return
+	}
+	token := query.Get("continuation-token")
+	l.tokens = append(l.tokens, token)
+
+	start := 0
+	if token != "" {
+		fmt.Sscanf(token, "page-%d", &start)
+	}
+	end := min(start+2, len(l.keys))
+
+	fmt.Fprint(w, `<ListBucketResult><EncodingType>url</EncodingType>`)
+	for _, key := range l.keys[start:end] {
+		fmt.Fprintf(w, `<Contents><Key>%s</Key><Size>1</Size></Contents>`, key)
+	}
+	if end < len(l.keys) {
+		fmt.Fprintf(w, `<IsTruncated>true</IsTruncated><NextContinuationToken>page-%d</NextContinuationToken>`, end)
+	} else {
+		fmt.Fprint(w, `<IsTruncated>false</IsTruncated>`)
+	}
+	fmt.Fprint(w, `</ListBucketResult>`)
+}
+
+func TestEachObjectFollowsContinuationToken(t *testing.T) {
+	listing := &pagedListing{keys: []string{"a", "b%20c", "d", "e"}}
+	client := newTestClient(t, listing)
+
+	var keys []string
+	err := client.EachObject(context.Background(), "bucket", nil, func(object ObjectInfo) error {
+		keys = append(keys, object.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a", "b c", "d", "e"}; !slices.Equal(keys, want) {
+		t.Errorf("got keys %q, want %q", keys, want)
+	}
+	if want := []string{"", "page-2"}; !slices.Equal(listing.tokens, want) {
+		t.Errorf("got continuation tokens %q, want %q", listing.tokens, want)
+	}
+}
+
+func TestEachObjectStopsOnCallbackError(t *testing.T) {
+	listing := &pagedListing{keys: []string{"a", "b", "c", "d"}}
+	client := newTestClient(t, listing)
+
+	errStop := errors.New("stop")
+	err := client.EachObject(context.Background(), "bucket", nil, func(object ObjectInfo) error {
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("got error %v, want %v", err, errStop)
+	}
+	if len(listing.tokens) != 1 {
+		t.Errorf("got %d page requests, want 1", len(listing.tokens))
+	}
+}
@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MoveObject copies an object to a new location and deletes the source afterwards.
+// The source is only deleted once the copy has been confirmed with an ETag.
+// The copy fails with ErrPreconditionFailed if the destination already exists,
+// so an existing object is never overwritten. Moving an object onto itself is a no-op.
+func (c *Client) MoveObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string) error {
+	if srcBucket == dstBucket && srcKey == dstKey {
+		return nil
+	}
+
+	result, err := c.CopyObject(ctx, srcBucket, srcKey, dstBucket, dstKey, &CopyOptions{IfNoneMatch: "*"})
+	if err != nil {
+		return err
+	}
+	if result.ETag == "" {
+		return fmt.Errorf("failed to confirm copy of %s/%s: no ETag returned", srcBucket, srcKey)
+	}
+
+	resp, err := c.DeleteObject(ctx, srcBucket, srcKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete source after copy: %w", err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// MovePrefix moves all objects below srcPrefix to the same keys below
+// dstPrefix with up to concurrency moves in parallel, e.g. to rename a
+// folder. Each object is moved with MoveObject, so a source is only deleted
+// after its copy was confirmed and existing destinations are not overwritten.
+// Objects that failed to move are left in place, their errors are joined into
+// the returned error together with the number of moved objects. The
+// concurrency is reduced while the service asks to slow down and grows back
+// after sustained successes, but never above concurrency.
+func (c *Client) MovePrefix(ctx context.Context, bucketName, srcPrefix, dstPrefix string, concurrency int) (int, error) {
+	if srcPrefix == dstPrefix {
+		return 0, nil
+	}
+	if strings.HasPrefix(dstPrefix, srcPrefix) {
+		return 0, fmt.Errorf("destination prefix %q is inside source prefix %q", dstPrefix, srcPrefix)
+	}
+	concurrency = max(concurrency, 1)
+	limiter := newAdaptiveLimiter(concurrency, 1, concurrency)
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		moved int
+		errs  []error
+	)
+	for object, err := range c.ObjectsSeq(ctx, bucketName, srcPrefix) {
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			break
+		}
+		// acquire before starting the move, so the listing does not run ahead
+		if err := limiter.acquire(ctx); err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			dstKey := dstPrefix + strings.TrimPrefix(object.Key, srcPrefix)
+			err := c.MoveObject(ctx, bucketName, object.Key, bucketName, dstKey)
+			limiter.release(err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("object %s: %w", object.Key, err))
+				return
+			}
+			moved++
+		}()
+	}
+	wg.Wait()
+
+	return moved, errors.Join(errs...)
+}
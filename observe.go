@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"time"
+)
+
+// RequestInfo describes a single attempt of a request, as passed to Config.RequestHook.
+type RequestInfo struct {
+	Method string
+	// URL of the request, including the query
+	URL string
+	// Status code of the response, 0 if no response was received
+	StatusCode int
+	// Number of the attempt, 0 for the first one
+	Attempt  int
+	Duration time.Duration
+	// Error of the attempt, including error responses of the service
+	Err error
+	// Metadata attached to the context with WithRequestMetadata
+	Metadata map[string]string
+}
+
+// requestMetadataKey is the context key of the metadata of WithRequestMetadata.
+type requestMetadataKey struct{}
+
+// WithRequestMetadata attaches metadata, e.g. a tenant id, to all requests made
+// with the returned context. The metadata is only passed to Config.RequestHook
+// and never sent to the service.
+func WithRequestMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, maps.Clone(metadata))
+}
+
+// RequestMetadata returns the metadata attached to ctx with WithRequestMetadata.
+func RequestMetadata(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(requestMetadataKey{}).(map[string]string)
+	return metadata
+}
+
+// observe reports a finished attempt of req to the configured request hook.
+// Retries are also counted for the transfer the request belongs to.
+func (c *Client) observe(req *http.Request, resp *http.Response, err error, attempt int, duration time.Duration) {
+	if attempt > 0 {
+		countRetry(req.Context())
+	}
+	if c.config.RequestHook == nil {
+		return
+	}
+
+	info := RequestInfo{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Attempt:  attempt,
+		Duration: duration,
+		Err:      err,
+		Metadata: RequestMetadata(req.Context()),
+	}
+	if resp != nil {
+		info.StatusCode = resp.StatusCode
+	}
+	c.config.RequestHook(req.Context(), info)
+}
@@ -0,0 +1,345 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ObjectOption configures a single object operation.
+type ObjectOption func(*objectOptions)
+
+// objectOptions collects the settings of all ObjectOptions passed to an operation.
+type objectOptions struct {
+	versionId         string
+	requestPayer      bool
+	sse               string
+	sseKMSKeyId       string
+	checksumAlgorithm string
+	checksum          string
+	contentType       string
+	sseCustomerKey    []byte
+	ifMatch           string
+	ifNoneMatch       string
+	ifModifiedSince   time.Time
+	ifUnmodifiedSince time.Time
+	contentMD5        bool
+	decompress        bool
+	userMetadata      map[string]string
+	storageClass      string
+	partNumber        int
+	lockMode          string
+	lockRetainUntil   time.Time
+	legalHold         *bool
+	tags              map[string]string
+	chunkSize         int
+}
+
+// WithVersionID addresses a specific version of the object.
+func WithVersionID(versionId string) ObjectOption {
+	return func(o *objectOptions) {
+		o.versionId = versionId
+	}
+}
+
+// WithPartNumber addresses a single part of an object uploaded in parts.
+// A HEAD request for part 1 reports the number of parts of the object.
+func WithPartNumber(partNumber int) ObjectOption {
+	return func(o *objectOptions) {
+		o.partNumber = partNumber
+	}
+}
+
+// WithRequestPayer confirms that the requester pays for the request on
+// requester pays buckets.
+func WithRequestPayer() ObjectOption {
+	return func(o *objectOptions) {
+		o.requestPayer = true
+	}
+}
+
+// WithSSE requests server-side encryption for uploads, e.g. "AES256" or
+// "aws:kms" with an optional KMS key id. It is ignored on reads.
+func WithSSE(algorithm string, kmsKeyId string) ObjectOption {
+	return func(o *objectOptions) {
+		o.sse = algorithm
+		o.sseKMSKeyId = kmsKeyId
+	}
+}
+
+// WithChecksum sends a precomputed base64 checksum of the given algorithm
+// (CRC32, CRC32C, CRC64NVME, SHA1 or SHA256) with an upload.
+// Without a value PutObject computes the checksum itself, PutObjectStream
+// computes it while streaming and sends it as trailer after the body, and the
+// creation of a multipart upload only announces the algorithm.
+// On reads it enables the checksum mode so the stored checksums are returned.
+func WithChecksum(algorithm string, value string) ObjectOption {
+	return func(o *objectOptions) {
+		o.checksumAlgorithm = algorithm
+		o.checksum = value
+	}
+}
+
+// WithSSECustomerKey encrypts an upload with a customer-provided 256-bit key
+// (SSE-C). Objects encrypted this way can only be read with the same key.
+func WithSSECustomerKey(key []byte) ObjectOption {
+	return func(o *objectOptions) {
+		o.sseCustomerKey = key
+	}
+}
+
+// WithIfMatch makes a request conditional on the current ETag of the object.
+// It fails with ErrPreconditionFailed if the object was changed.
+func WithIfMatch(etag string) ObjectOption {
+	return func(o *objectOptions) {
+		o.ifMatch = etag
+	}
+}
+
+// WithIfNoneMatch makes a request conditional on the object not matching etag,
+// "*" only creates the object if it does not exist yet.
+// Writes fail with ErrPreconditionFailed otherwise.
+func WithIfNoneMatch(etag string) ObjectOption {
+	return func(o *objectOptions) {
+		o.ifNoneMatch = etag
+	}
+}
+
+// WithIfModifiedSince only returns the object if it was modified after t.
+// Otherwise the request fails with ErrNotModified.
+func WithIfModifiedSince(t time.Time) ObjectOption {
+	return func(o *objectOptions) {
+		o.ifModifiedSince = t
+	}
+}
+
+// WithIfUnmodifiedSince only returns the object if it was not modified after t.
+// Otherwise the request fails with ErrPreconditionFailed.
+func WithIfUnmodifiedSince(t time.Time) ObjectOption {
+	return func(o *objectOptions) {
+		o.ifUnmodifiedSince = t
+	}
+}
+
+// WithContentMD5 sends the MD5 digest of the payload with PutObject, so the
+// service rejects uploads corrupted in transit. Streamed bodies of
+// PutObjectStream and UploadPart must implement io.ReadSeeker, as they are
+// read once to compute the digest.
+func WithContentMD5() ObjectOption {
+	return func(o *objectOptions) {
+		o.contentMD5 = true
+	}
+}
+
+// WithTransparentDecompression lets the HTTP transport request gzip and
+// decompress the response. By default object reads ask for the identity
+// encoding, so the bytes received match the stored object and its ETag.
+func WithTransparentDecompression() ObjectOption {
+	return func(o *objectOptions) {
+		o.decompress = true
+	}
+}
+
+// WithUserMetadata stores the entries as user-defined metadata with an upload,
+// sent as x-amz-meta-<key> headers with lowercase keys.
+func WithUserMetadata(metadata map[string]string) ObjectOption {
+	return func(o *objectOptions) {
+		o.userMetadata = metadata
+	}
+}
+
+// WithStorageClass stores an upload in the given storage class, e.g.
+// STANDARD_IA, GLACIER or INTELLIGENT_TIERING. The value is passed through
+// unchecked, so newer classes can be used as well.
+func WithStorageClass(storageClass string) ObjectOption {
+	return func(o *objectOptions) {
+		o.storageClass = storageClass
+	}
+}
+
+// WithContentType sets the Content-Type of an upload. Without it the type is
+// derived from the extension of the object key.
+func WithContentType(contentType string) ObjectOption {
+	return func(o *objectOptions) {
+		o.contentType = contentType
+	}
+}
+
+// WithObjectLock retains an upload in the given lock mode (GOVERNANCE or
+// COMPLIANCE) until retainUntil. For multipart uploads it is passed to
+// CreateMultipartUpload, so the object is locked as soon as it is completed.
+// The bucket must have object lock enabled. S3 requires Content-MD5 or a
+// checksum with such uploads; unless a checksum is requested, Content-MD5 is
+// sent with buffered uploads and parts, and streamed uploads send a CRC32
+// trailing checksum.
+func WithObjectLock(mode string, retainUntil time.Time) ObjectOption {
+	return func(o *objectOptions) {
+		o.lockMode = mode
+		o.lockRetainUntil = retainUntil
+	}
+}
+
+// WithLegalHold places or explicitly omits a legal hold on an upload. Like
+// WithObjectLock, it makes uploads carry Content-MD5 or a checksum.
+func WithLegalHold(on bool) ObjectOption {
+	return func(o *objectOptions) {
+		o.legalHold = &on
+	}
+}
+
+// objectLocked reports whether the options set object lock retention or a
+// legal hold, with which S3 rejects uploads lacking Content-MD5 or a checksum.
+func (o objectOptions) objectLocked() bool {
+	return o.lockMode != "" || o.legalHold != nil
+}
+
+// WithTagging stores the tags with an upload, sent URL encoded as x-amz-tagging.
+func WithTagging(tags map[string]string) ObjectOption {
+	return func(o *objectOptions) {
+		o.tags = tags
+	}
+}
+
+// WithChunkSize overrides Config.ChunkSize for a streamed upload, e.g. to
+// read a large body in bigger chunks.
+func WithChunkSize(size int) ObjectOption {
+	return func(o *objectOptions) {
+		o.chunkSize = size
+	}
+}
+
+func newObjectOptions(opts []ObjectOption) objectOptions {
+	var o objectOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// query returns the query parameters for the options.
+func (o objectOptions) query() map[string]string {
+	query := make(map[string]string)
+	if o.versionId != "" {
+		query["versionId"] = o.versionId
+	}
+	if o.partNumber > 0 {
+		query["partNumber"] = strconv.Itoa(o.partNumber)
+	}
+	return query
+}
+
+// setHeaders sets the request headers for the options.
+func (o objectOptions) setHeaders(req *http.Request) {
+	upload := req.Method == http.MethodPut || req.Method == http.MethodPost
+
+	if upload {
+		contentType := o.contentType
+		if contentType == "" {
+			contentType = resolveContentType(req.URL.Path)
+		}
+		req.Header.Set("Content-Type", contentType)
+		setUserMetadata(req, o.userMetadata)
+		if o.storageClass != "" {
+			req.Header.Set("x-amz-storage-class", o.storageClass)
+		}
+		o.setObjectLockHeaders(req)
+		if len(o.tags) > 0 {
+			req.Header.Set("x-amz-tagging", encodeTags(o.tags))
+		}
+	}
+	o.setPartHeaders(req)
+	if req.Method == http.MethodGet && !o.decompress {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
+	o.setConditionalHeaders(req)
+	if upload && o.sse != "" {
+		req.Header.Set("x-amz-server-side-encryption", o.sse)
+		if o.sseKMSKeyId != "" {
+			req.Header.Set("x-amz-server-side-encryption-aws-kms-key-id", o.sseKMSKeyId)
+		}
+	}
+	if o.checksumAlgorithm != "" {
+		if upload && o.checksum == "" {
+			req.Header.Set("x-amz-checksum-algorithm", strings.ToUpper(o.checksumAlgorithm))
+		} else if upload {
+			req.Header.Set("x-amz-checksum-"+strings.ToLower(o.checksumAlgorithm), o.checksum)
+		} else {
+			req.Header.Set("x-amz-checksum-mode", "ENABLED")
+		}
+	}
+}
+
+// setPartHeaders sets the headers that also apply to the parts of a
+// multipart upload: the SSE-C key and the requester pays confirmation.
+func (o objectOptions) setPartHeaders(req *http.Request) {
+	if o.sseCustomerKey != nil {
+		setSSECustomerHeaders(req, "x-amz-server-side-encryption-customer-", o.sseCustomerKey)
+	}
+	if o.requestPayer {
+		req.Header.Set("x-amz-request-payer", "requester")
+	}
+}
+
+// setObjectLockHeaders sets the retention and legal hold headers of an upload.
+func (o objectOptions) setObjectLockHeaders(req *http.Request) {
+	if o.lockMode != "" {
+		req.Header.Set("x-amz-object-lock-mode", o.lockMode)
+		req.Header.Set("x-amz-object-lock-retain-until-date", o.lockRetainUntil.UTC().Format(time.RFC3339))
+	}
+	if o.legalHold != nil {
+		status := "OFF"
+		if *o.legalHold {
+			status = "ON"
+		}
+		req.Header.Set("x-amz-object-lock-legal-hold", status)
+	}
+}
+
+// setConditionalHeaders sets the If-* headers for the options.
+func (o objectOptions) setConditionalHeaders(req *http.Request) {
+	// ETags are handled without quotes and only quoted on the wire
+	if o.ifMatch != "" {
+		req.Header.Set("If-Match", quoteETag(o.ifMatch))
+	}
+	if o.ifNoneMatch == "*" {
+		req.Header.Set("If-None-Match", o.ifNoneMatch)
+	} else if o.ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", quoteETag(o.ifNoneMatch))
+	}
+	if !o.ifModifiedSince.IsZero() {
+		req.Header.Set("If-Modified-Since", o.ifModifiedSince.UTC().Format(http.TimeFormat))
+	}
+	if !o.ifUnmodifiedSince.IsZero() {
+		req.Header.Set("If-Unmodified-Since", o.ifUnmodifiedSince.UTC().Format(http.TimeFormat))
+	}
+}
+
+// resolveContentType returns the MIME type registered for the extension of
+// the object key, or application/octet-stream for unknown extensions.
+func resolveContentType(objectName string) string {
+	if contentType := mime.TypeByExtension(path.Ext(objectName)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// setUserMetadata sets an x-amz-meta-* header for every metadata entry.
+func setUserMetadata(req *http.Request, metadata map[string]string) {
+	for key, value := range metadata {
+		req.Header.Set(metadataHeaderPrefix+strings.ToLower(key), value)
+	}
+}
+
+// setSSECustomerHeaders sets the algorithm, the base64 encoded key and its MD5
+// digest of an SSE-C key with the given header prefix.
+func setSSECustomerHeaders(req *http.Request, prefix string, key []byte) {
+	digest := md5.Sum(key)
+	req.Header.Set(prefix+"algorithm", "AES256")
+	req.Header.Set(prefix+"key", base64.StdEncoding.EncodeToString(key))
+	req.Header.Set(prefix+"key-MD5", base64.StdEncoding.EncodeToString(digest[:]))
+}
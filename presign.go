@@ -0,0 +1,45 @@
+package s3
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// longest validity of a presigned URL accepted by S3
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// PresignGetObject returns a URL that allows downloading the object without
+// credentials until the expiry has passed.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/ShareObjectPreSignedURL.html
+func (c *Client) PresignGetObject(bucketName, objectName string, expiry time.Duration) (string, error) {
+	return c.presign(http.MethodGet, bucketName, objectName, expiry)
+}
+
+// PresignPutObject returns a URL that allows uploading the object without
+// credentials until the expiry has passed.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/PresignedUrlUploadObject.html
+func (c *Client) PresignPutObject(bucketName, objectName string, expiry time.Duration) (string, error) {
+	return c.presign(http.MethodPut, bucketName, objectName, expiry)
+}
+
+// presign builds a URL for the request signed via query parameters.
+func (c *Client) presign(method, bucketName, objectName string, expiry time.Duration) (string, error) {
+	if expiry < time.Second || expiry > maxPresignExpiry {
+		return "", fmt.Errorf("expiry must be between 1 second and %s, got %s", maxPresignExpiry, expiry)
+	}
+
+	endpointURL, err := c.buildEndpoint(bucketName, objectName, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(method, endpointURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	presignRequest(req, c.bucketRegion(bucketName), c.signingService(), c.config.AccessKey, c.config.SecretKey, c.config.SessionToken, expiry, c.signingTime())
+
+	return req.URL.String(), nil
+}
@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// EnsureRegion looks up the region of a bucket and uses it to sign all
+// further requests to that bucket. It returns the detected region.
+// The region is cached per bucket, so only the first call does a lookup.
+func (c *Client) EnsureRegion(ctx context.Context, bucketName string) (string, error) {
+	c.regionsMu.RLock()
+	region, ok := c.regions[bucketName]
+	c.regionsMu.RUnlock()
+	if ok {
+		return region, nil
+	}
+
+	region, err := c.lookupBucketRegion(ctx, bucketName)
+	if err != nil {
+		return "", err
+	}
+
+	c.regionsMu.Lock()
+	if c.regions == nil {
+		c.regions = make(map[string]string)
+	}
+	c.regions[bucketName] = region
+	c.regionsMu.Unlock()
+
+	return region, nil
+}
+
+// bucketRegion returns the signing region for a bucket.
+func (c *Client) bucketRegion(bucketName string) string {
+	c.regionsMu.RLock()
+	defer c.regionsMu.RUnlock()
+
+	if region, ok := c.regions[bucketName]; ok {
+		return region
+	}
+	return c.config.Region
+}
+
+// lookupBucketRegion determines the region of a bucket. S3 reports it in the
+// x-amz-bucket-region header of a HEAD request even when the request was
+// signed for the wrong region, GetBucketLocation is the fallback for services
+// that omit the header.
+func (c *Client) lookupBucketRegion(ctx context.Context, bucketName string) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, bucketName, "", nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	c.signRequest(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	resp.Body.Close()
+
+	if region := resp.Header.Get("x-amz-bucket-region"); region != "" {
+		return region, nil
+	}
+
+	location, err := c.GetBucketLocation(ctx, bucketName)
+	if err != nil {
+		return "", err
+	}
+
+	switch location.Region {
+	case "":
+		// buckets in us-east-1 have an empty location constraint
+		return "us-east-1", nil
+	case "EU":
+		return "eu-west-1", nil
+	}
+	return location.Region, nil
+}
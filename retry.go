@@ -0,0 +1,106 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// default delay before the first retry
+const defaultRetryBaseDelay = 100 * time.Millisecond
+
+// upper bound of the delay between two attempts
+const maxRetryDelay = 20 * time.Second
+
+// shouldRetry reports whether a failed request may be sent again.
+// Only idempotent requests whose body can be rewound are retried, the error
+// itself is judged by the configured RetryableFunc.
+func (c *Client) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if !rewindable(req) {
+		return false
+	}
+
+	retryable := c.config.RetryableFunc
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	return retryable(req, resp, err)
+}
+
+// DefaultRetryable is the default retry policy. It retries server errors,
+// throttling and network failures.
+func DefaultRetryable(req *http.Request, resp *http.Response, err error) bool {
+	if resp != nil {
+		return resp.StatusCode >= 500 || errors.Is(err, ErrSlowDown)
+	}
+	var urlError *url.Error
+	return errors.As(err, &urlError)
+}
+
+// isTransient reports whether err is a server error, throttling or a
+// network failure, for retrying requests outside of do.
+func isTransient(err error) bool {
+	var errorResponse ErrorResponse
+	if errors.As(err, &errorResponse) {
+		return errorResponse.StatusCode >= 500 || errors.Is(err, ErrSlowDown)
+	}
+	var urlError *url.Error
+	return errors.As(err, &urlError)
+}
+
+// retryDelay returns the backoff before the given retry attempt, starting at
+// 0, as an exponentially growing delay with jitter.
+func (c *Client) retryDelay(attempt int) time.Duration {
+	delay := c.config.RetryBaseDelay
+	if delay <= 0 {
+		delay = defaultRetryBaseDelay
+	}
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryDelay)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// rewindable reports whether the request can be sent again, i.e. it has no
+// body or a body that can be recreated.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewindRequest returns a copy of the request with a fresh body for another attempt.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
+
+// sleepContext waits for the given duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// flakyHandler fails the first failures requests with 503 and records the
+// bodies of all attempts.
+type flakyHandler struct {
+	failures int
+	bodies   []string
+}
+
+func (h *flakyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	h.bodies = append(h.bodies, string(body))
+	if len(h.bodies) <= h.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+func TestRetryFailTwiceThenSucceed(t *testing.T) {
+	handler := &flakyHandler{failures: 2}
+	client := newTestClient(t, handler)
+	client.config.MaxRetries = 3
+	client.config.RetryBaseDelay = time.Millisecond
+
+	if err := client.PutObject(context.Background(), "bucket", "key", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(handler.bodies) != 3 {
+		t.Fatalf("got %d attempts, want 3", len(handler.bodies))
+	}
+	for i, body := range handler.bodies {
+		if body != "data" {
+			t.Errorf("attempt %d sent body %q, want %q", i+1, body, "data")
+		}
+	}
+}
+
+func TestRetryGivesUpAfterMaxRetries(t *testing.T) {
+	handler := &flakyHandler{failures: 3}
+	client := newTestClient(t, handler)
+	client.config.MaxRetries = 2
+	client.config.RetryBaseDelay = time.Millisecond
+
+	_, err := client.HeadObject(context.Background(), "bucket", "key")
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	if len(handler.bodies) != 3 {
+		t.Errorf("got %d attempts, want 3", len(handler.bodies))
+	}
+}
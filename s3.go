@@ -5,18 +5,22 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// default chunk size
-const chunkSize = 4096
+// default number of bytes read from a streamed request body at once
+const defaultChunkSize = 64 * 1024
 
 // build
 func buildContentHash(data []byte) (string, error) {
@@ -24,12 +28,54 @@ func buildContentHash(data []byte) (string, error) {
 	return base64.StdEncoding.EncodeToString(hash[:]), nil
 }
 
+// buildReaderContentHash returns the Content-MD5 of the rest of r and seeks
+// r back to where it was, so the content can be sent afterwards.
+func buildReaderContentHash(r io.Reader) (string, error) {
+	seeker, ok := r.(io.ReadSeeker)
+	if !ok {
+		return "", fmt.Errorf("Content-MD5 requires a body implementing io.ReadSeeker")
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash body: %w", err)
+	}
+	hash := md5.New()
+	if _, err := io.Copy(hash, seeker); err != nil {
+		return "", fmt.Errorf("failed to hash body: %w", err)
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind body: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// trimETag removes the double quotes S3 wraps ETags in.
+func trimETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
+
+// quoteETag returns the ETag wrapped in double quotes, as sent by S3.
+func quoteETag(etag string) string {
+	return `"` + trimETag(etag) + `"`
+}
+
 // New creates a new Client.
 func New(config Config, httpclient *http.Client) (*Client, error) {
-	u, err := url.Parse(config.Endpoint)
+	endpoint := config.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		// default to https when the endpoint is given as host[:port]
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse endpoint: %w", err)
 	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported endpoint scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("endpoint %q has no host", config.Endpoint)
+	}
 	client := &Client{
 		config:      config,
 		endpointURL: u.String(),
@@ -45,93 +91,207 @@ func (c *Client) buildEndpoint(bucketName, path string, query map[string]string)
 		return "", fmt.Errorf("failed to parse endpoint: %w", err)
 	}
 	if bucketName != "" {
-		u.Host = bucketName + "." + u.Host
+		if c.config.PathStyle || usePathStyle(u.Hostname()) {
+			if path == "" {
+				path = bucketName
+			} else {
+				path = bucketName + "/" + strings.TrimPrefix(path, "/")
+			}
+		} else {
+			u.Host = joinHostPort(bucketName+"."+u.Hostname(), u.Port())
+		}
 	}
 	q := u.Query()
 	for k, v := range query {
 		q.Set(k, v)
 	}
-	u.RawQuery = q.Encode()
+	u.RawQuery = canonicalQuery(q)
 	if path == "" {
 		return u.JoinPath("/").String(), nil
 	}
 
-	url := u.JoinPath(path)
-	url.Path = url.EscapedPath()
+	// keys are used verbatim, without cleaning, and every segment is encoded
+	// as in the canonical URI, so the sent path and the signed path match
+	path = strings.TrimPrefix(path, "/")
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/" + uriEncode(path, false)
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + path
 
-	return url.String(), nil
+	return u.String(), nil
+}
+
+// usePathStyle reports whether the bucket has to be addressed in the path
+// instead of as a subdomain of the given endpoint host.
+// IP addresses can not carry a bucket subdomain, so they always use path-style.
+func usePathStyle(host string) bool {
+	return host == "localhost" || net.ParseIP(host) != nil
+}
+
+// joinHostPort combines host and port, keeping the host as is when there is no port.
+func joinHostPort(host, port string) string {
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
 }
 
 // Signed Payload
 func (c *Client) newRequest(ctx context.Context, method, bucketName, path string, query map[string]string, body []byte) (*http.Request, error) {
-	now := time.Now().UTC()
 	endpointURL, err := c.buildEndpoint(bucketName, path, query)
 	if err != nil {
 		return nil, err
 	}
 
+	ctx = context.WithValue(ctx, signingRegionKey{}, c.bucketRegion(bucketName))
 	req, err := http.NewRequestWithContext(ctx, method, endpointURL, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	payloadHash := getPayloadHash(&body)
-	req.Header.Set("Authorization", getAuthorizationHeader(req, payloadHash, c.config.Region, c.config.AccessKey, c.config.SecretKey, now))
-	req.Header.Set("x-amz-content-sha256", payloadHash)
-	req.Header.Set("x-amz-date", now.Format(timeFormat))
+	req.Header.Set("x-amz-content-sha256", getPayloadHash(&body))
+	if c.config.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", c.config.SessionToken)
+	}
 	req.Header.Set("User-Agent", userAgent)
-	req.Header.Set("Content-Length", strconv.Itoa(len(body)))
 	return req, nil
 }
 
 // Unsigned Payload
 func (c *Client) newRequestStream(ctx context.Context, method, bucketName, path string, query map[string]string, body io.Reader) (*http.Request, error) {
-	now := time.Now().UTC()
 	endpointURL, err := c.buildEndpoint(bucketName, path, query)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, endpointURL, newChunkReader(body))
+	if _, ok := body.(*chunkReader); !ok {
+		body = newChunkReader(body, c.chunkSize())
+	}
+
+	ctx = context.WithValue(ctx, signingRegionKey{}, c.bucketRegion(bucketName))
+	req, err := http.NewRequestWithContext(ctx, method, endpointURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", getAuthorizationHeader(req, "UNSIGNED-PAYLOAD", c.config.Region, c.config.AccessKey, c.config.SecretKey, now))
 	req.Header.Set("x-amz-content-sha256", "UNSIGNED-PAYLOAD")
-	req.Header.Set("x-amz-date", now.Format(timeFormat))
+	if c.config.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", c.config.SessionToken)
+	}
 	req.Header.Set("User-Agent", userAgent)
-	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Type", resolveContentType(path))
 
 	return req, nil
 }
 
-// do sends the request and handles any error response.
+// signingRegionKey is the context key of the region a request is signed for.
+type signingRegionKey struct{}
+
+// signRequest sets the date and the Authorization header right before the
+// request is sent, so headers added after creating the request are signed
+// and retries carry a fresh date.
+func (c *Client) signRequest(req *http.Request) {
+	now := c.signingTime()
+	region, ok := req.Context().Value(signingRegionKey{}).(string)
+	if !ok {
+		region = c.config.Region
+	}
+	req.Header.Set("x-amz-date", now.Format(timeFormat))
+	req.Header.Set("Authorization", getAuthorizationHeader(req, req.Header.Get("x-amz-content-sha256"), region, c.signingService(), c.config.AccessKey, c.config.SecretKey, now))
+}
+
+// signingService returns the service name of the credential scope.
+func (c *Client) signingService() string {
+	if c.config.SigningService != "" {
+		return c.config.SigningService
+	}
+	return defaultSigningService
+}
+
+// do sends the request, retries transient failures and handles any error response.
+// A request rejected for clock skew is resent once right away with the
+// server time, this retry does not count against MaxRetries.
 func (c *Client) do(req *http.Request) (*http.Response, error) {
+	retries := c.config.MaxRetries
+	skewRetried := false
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		resp, err := c.send(req)
+		c.observe(req, resp, err, attempt, time.Since(start))
+		if err == nil {
+			return resp, nil
+		}
+		if !skewRetried && c.correctClockSkew(resp, err) && rewindable(req) {
+			skewRetried = true
+			retries++
+		} else {
+			if attempt >= retries || !c.shouldRetry(req, resp, err) {
+				return nil, err
+			}
+			if err := sleepContext(req.Context(), c.retryDelay(attempt)); err != nil {
+				return nil, err
+			}
+		}
+		req, err = rewindRequest(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send executes a request once and turns error status codes into errors.
+// The response of a failed request is returned with its body closed.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	c.signRequest(req)
 	resp, err := c.httpClient.Do(req)
-	if err != nil && err != io.EOF {
+	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
+	if resp == nil {
+		return nil, fmt.Errorf("failed to send request: no response")
+	}
+	c.recordServerTime(resp)
+	if resp.StatusCode == http.StatusNotModified {
+		// a 304 never carries an error document
+		resp.Body.Close()
+		return resp, ErrorResponse{StatusCode: resp.StatusCode, Code: "NotModified", Message: http.StatusText(resp.StatusCode)}
+	}
 	if resp.StatusCode >= 300 {
-		contentLength := resp.Header.Get("Content-Length")
-		length, err := strconv.Atoi(contentLength)
+		defer resp.Body.Close()
+		errorResponse, err := parseErrorResponse(resp)
 		if err != nil {
-			return nil, fmt.Errorf("failed to extract content-length: %w", err)
+			return resp, err
 		}
-		if length > 0 {
-			var errorResponse ErrorResponse
-			if err := xml.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
-				return nil, fmt.Errorf("failed to parse response: %w", err)
-			}
-			return nil, errorResponse
+		if resp.StatusCode == http.StatusPreconditionFailed {
+			return resp, newPreconditionFailedError(resp, errorResponse)
 		}
-
-		return nil, fmt.Errorf("received an unknown response error and no details: %w", err)
+		if resp.Header.Get("x-amz-delete-marker") == "true" {
+			return resp, &DeleteMarkerError{ErrorResponse: errorResponse, VersionId: resp.Header.Get("x-amz-version-id")}
+		}
+		return resp, errorResponse
 	}
+	resp.Body = newContextBody(req.Context(), resp.Body)
 
 	return resp, nil
 }
 
+// parseErrorResponse decodes the error document of a failed request.
+// Responses without a body, e.g. to HEAD requests, get a code derived from the status.
+func parseErrorResponse(resp *http.Response) (ErrorResponse, error) {
+	errorResponse := ErrorResponse{StatusCode: resp.StatusCode}
+	if resp.ContentLength != 0 {
+		if err := newXMLDecoder(resp.Body).Decode(&errorResponse); err != nil && err != io.EOF {
+			return errorResponse, fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	if errorResponse.Code == "" {
+		errorResponse.Code = strings.ReplaceAll(http.StatusText(resp.StatusCode), " ", "")
+		errorResponse.Message = http.StatusText(resp.StatusCode)
+	}
+	return errorResponse, nil
+}
+
 // Create a bucket
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateBucket.html
 func (c *Client) CreateBucket(ctx context.Context, name string) error {
@@ -141,8 +301,54 @@ func (c *Client) CreateBucket(ctx context.Context, name string) error {
 	}
 
 	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
 	resp.Body.Close()
-	return err
+
+	return nil
+}
+
+// HeadBucket checks whether a bucket exists and is accessible.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadBucket.html
+func (c *Client) HeadBucket(ctx context.Context, bucketName string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, bucketName, "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetBucketLocation returns the region the bucket resides in.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLocation.html
+func (c *Client) GetBucketLocation(ctx context.Context, bucketName string) (*LocationConstraint, error) {
+	var location LocationConstraint
+	query := make(map[string]string)
+	query["location"] = ""
+
+	req, err := c.newRequest(ctx, http.MethodGet, bucketName, "", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = newXMLDecoder(resp.Body).Decode(&location)
+	if err != nil {
+		return nil, err
+	}
+
+	return &location, nil
 }
 
 // ListBuckets returns a list of buckets.
@@ -160,7 +366,7 @@ func (c *Client) ListBuckets(ctx context.Context) (*ListBucketsResponse, error)
 	}
 	defer resp.Body.Close()
 
-	if err := xml.NewDecoder(resp.Body).Decode(&results); err != nil {
+	if err := newXMLDecoder(resp.Body).Decode(&results); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
@@ -184,9 +390,13 @@ func (c *Client) ListObjects(ctx context.Context, bucketName string) (*ListObjec
 	}
 	defer resp.Body.Close()
 
-	if err := xml.NewDecoder(resp.Body).Decode(&results); err != nil {
+	if err := newXMLDecoder(resp.Body).Decode(&results); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	results.trimETags()
+	if err := results.decodeURLEncoding(); err != nil {
+		return nil, err
+	}
 
 	resp.Body.Close()
 
@@ -194,8 +404,14 @@ func (c *Client) ListObjects(ctx context.Context, bucketName string) (*ListObjec
 }
 
 // ListObjectsV2 returns a list of objects within a specified bucket.
+// Keys of listings requested with encoding-type=url are decoded.
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectsV2.html
 func (c *Client) ListObjectsV2(ctx context.Context, bucketName string, query map[string]string) (*ListObjectsResponse, error) {
+	if query == nil {
+		query = make(map[string]string)
+	}
+
+	query["list-type"] = "2"
 
 	req, err := c.newRequest(ctx, http.MethodGet, bucketName, "", query, nil)
 	if err != nil {
@@ -209,15 +425,53 @@ func (c *Client) ListObjectsV2(ctx context.Context, bucketName string, query map
 	defer resp.Body.Close()
 
 	var results ListObjectsResponse
-	if err := xml.NewDecoder(resp.Body).Decode(&results); err != nil {
+	if err := newXMLDecoder(resp.Body).Decode(&results); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	results.trimETags()
+	if err := results.decodeURLEncoding(); err != nil {
+		return nil, err
+	}
 
 	resp.Body.Close()
 
 	return &results, nil
 }
 
+// ListObjectsV2WithInput lists objects like ListObjectsV2 with the parameters
+// given as struct instead of a raw query.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectsV2.html
+func (c *Client) ListObjectsV2WithInput(ctx context.Context, bucketName string, input ListObjectsV2Input) (*ListObjectsResponse, error) {
+	return c.ListObjectsV2(ctx, bucketName, input.query())
+}
+
+// query returns the query parameters of the input.
+func (i ListObjectsV2Input) query() map[string]string {
+	query := make(map[string]string)
+	if i.Prefix != "" {
+		query["prefix"] = i.Prefix
+	}
+	if i.Delimiter != "" {
+		query["delimiter"] = i.Delimiter
+	}
+	if i.MaxKeys > 0 {
+		query["max-keys"] = strconv.Itoa(i.MaxKeys)
+	}
+	if i.StartAfter != "" {
+		query["start-after"] = i.StartAfter
+	}
+	if i.ContinuationToken != "" {
+		query["continuation-token"] = i.ContinuationToken
+	}
+	if i.FetchOwner {
+		query["fetch-owner"] = "true"
+	}
+	if i.EncodingType != "" {
+		query["encoding-type"] = i.EncodingType
+	}
+	return query
+}
+
 // ListObjectVersions returns a list of objects with metadata in a bucket
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectVersions.html
 func (c *Client) ListObjectVersions(ctx context.Context, bucketName string, query map[string]string) (*ListVersionsResult, error) {
@@ -240,9 +494,15 @@ func (c *Client) ListObjectVersions(ctx context.Context, bucketName string, quer
 	}
 	defer resp.Body.Close()
 
-	if err := xml.NewDecoder(resp.Body).Decode(&results); err != nil {
+	if err := newXMLDecoder(resp.Body).Decode(&results); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	for i := range results.Versions {
+		results.Versions[i].ETag = trimETag(results.Versions[i].ETag)
+	}
+	if err := results.decodeURLEncoding(); err != nil {
+		return nil, err
+	}
 
 	resp.Body.Close()
 
@@ -251,11 +511,13 @@ func (c *Client) ListObjectVersions(ctx context.Context, bucketName string, quer
 
 // HeadObject get object metadata, in this case the file size
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadObject.html
-func (c *Client) HeadObject(ctx context.Context, bucketName string, objectName string) (*http.Response, error) {
-	req, err := c.newRequest(ctx, http.MethodHead, bucketName, objectName, nil, nil)
+func (c *Client) HeadObject(ctx context.Context, bucketName string, objectName string, opts ...ObjectOption) (*http.Response, error) {
+	options := newObjectOptions(opts)
+	req, err := c.newRequest(ctx, http.MethodHead, bucketName, objectName, options.query(), nil)
 	if err != nil {
 		return nil, err
 	}
+	options.setHeaders(req)
 
 	resp, err := c.do(req)
 	if err != nil {
@@ -265,13 +527,66 @@ func (c *Client) HeadObject(ctx context.Context, bucketName string, objectName s
 	return resp, nil
 }
 
+// HeadObjectInfo returns the parsed object metadata of a HEAD request.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadObject.html
+func (c *Client) HeadObjectInfo(ctx context.Context, bucketName string, objectName string, opts ...ObjectOption) (*HeadObjectResult, error) {
+	resp, err := c.HeadObject(ctx, bucketName, objectName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	result := parseHeadObjectResult(resp)
+	return &result, nil
+}
+
+// prefix of the headers carrying user-defined object metadata
+const metadataHeaderPrefix = "x-amz-meta-"
+
+// parseHeadObjectResult extracts the object metadata from the response headers.
+func parseHeadObjectResult(resp *http.Response) HeadObjectResult {
+	result := HeadObjectResult{
+		ContentLength:      resp.ContentLength,
+		ContentType:        resp.Header.Get("Content-Type"),
+		CacheControl:       resp.Header.Get("Cache-Control"),
+		ContentDisposition: resp.Header.Get("Content-Disposition"),
+		ContentEncoding:    resp.Header.Get("Content-Encoding"),
+		ContentLanguage:    resp.Header.Get("Content-Language"),
+		ETag:               trimETag(resp.Header.Get("ETag")),
+		VersionId:          resp.Header.Get("x-amz-version-id"),
+		StorageClass:       resp.Header.Get("x-amz-storage-class"),
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		result.LastModified = lastModified
+	}
+	if count, err := strconv.Atoi(resp.Header.Get("x-amz-tagging-count")); err == nil {
+		result.TaggingCount = count
+	}
+	if count, err := strconv.Atoi(resp.Header.Get("x-amz-mp-parts-count")); err == nil {
+		result.PartsCount = count
+	}
+	for name, values := range resp.Header {
+		key, ok := strings.CutPrefix(strings.ToLower(name), metadataHeaderPrefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if result.Metadata == nil {
+			result.Metadata = make(map[string]string)
+		}
+		result.Metadata[key] = values[0]
+	}
+	return result
+}
+
 // GetObject fetches an object.
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html
-func (c *Client) GetObject(ctx context.Context, bucketName, objectName string) (io.ReadCloser, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, bucketName, objectName, nil, nil)
+func (c *Client) GetObject(ctx context.Context, bucketName, objectName string, opts ...ObjectOption) (io.ReadCloser, error) {
+	options := newObjectOptions(opts)
+	req, err := c.newRequest(ctx, http.MethodGet, bucketName, objectName, options.query(), nil)
 	if err != nil {
 		return nil, err
 	}
+	options.setHeaders(req)
 
 	resp, err := c.do(req)
 	if err != nil {
@@ -281,53 +596,249 @@ func (c *Client) GetObject(ctx context.Context, bucketName, objectName string) (
 	return resp.Body, nil
 }
 
-// GetObject fetches an object.
+// GetObjectWithInfo fetches an object together with its parsed metadata.
+// The caller is responsible for closing the returned body.
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html
-func (c *Client) GetObjectPart(ctx context.Context, bucketName, objectName string, start uint64, end uint64) (io.ReadCloser, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, bucketName, objectName, nil, nil)
+func (c *Client) GetObjectWithInfo(ctx context.Context, bucketName, objectName string, opts ...ObjectOption) (*GetObjectResult, error) {
+	options := newObjectOptions(opts)
+	req, err := c.newRequest(ctx, http.MethodGet, bucketName, objectName, options.query(), nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	options.setHeaders(req)
 
 	resp, err := c.do(req)
-	if err != nil && err != io.EOF {
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetObjectResult{
+		HeadObjectResult: parseHeadObjectResult(resp),
+		Body:             resp.Body,
+	}, nil
+}
+
+// GetObjectPart fetches a byte range of an object.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html
+func (c *Client) GetObjectPart(ctx context.Context, bucketName, objectName string, byteRange Range, opts ...ObjectOption) (io.ReadCloser, error) {
+	resp, err := c.getObjectRange(ctx, bucketName, objectName, byteRange, opts...)
+	if err != nil {
 		return nil, err
 	}
 
 	return resp.Body, nil
 }
 
+// getObjectRange requests a byte range of an object and returns the whole response.
+func (c *Client) getObjectRange(ctx context.Context, bucketName, objectName string, byteRange Range, opts ...ObjectOption) (*http.Response, error) {
+	options := newObjectOptions(opts)
+	req, err := c.newRequest(ctx, http.MethodGet, bucketName, objectName, options.query(), nil)
+	if err != nil {
+		return nil, err
+	}
+	options.setHeaders(req)
+	req.Header.Set("Range", byteRange.String())
+
+	return c.do(req)
+}
+
 // PutObject uploads an object to the specified bucket.
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObject.html
-func (c *Client) PutObject(ctx context.Context, bucketName, objectName string, data []byte) error {
+func (c *Client) PutObject(ctx context.Context, bucketName, objectName string, data []byte, opts ...ObjectOption) error {
+	_, err := c.PutObjectWithInfo(ctx, bucketName, objectName, data, opts...)
+	return err
+}
+
+// PutObjectWithInfo uploads an object like PutObject and returns the ETag and
+// version id assigned by the service.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObject.html
+func (c *Client) PutObjectWithInfo(ctx context.Context, bucketName, objectName string, data []byte, opts ...ObjectOption) (*PutObjectResult, error) {
+	options := newObjectOptions(opts)
+	if options.checksumAlgorithm != "" && options.checksum == "" {
+		checksum, err := ComputeChecksum(options.checksumAlgorithm, data)
+		if err != nil {
+			return nil, err
+		}
+		options.checksum = checksum
+	}
+
 	req, err := c.newRequest(ctx, http.MethodPut, bucketName, objectName, nil, data)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	options.setHeaders(req)
+
+	if options.contentMD5 || (options.objectLocked() && options.checksumAlgorithm == "") {
+		hash, err := buildContentHash(data)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-MD5", hash)
 	}
 
 	resp, err := c.do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	resp.Body.Close()
 
-	return nil
+	result := parsePutObjectResult(resp)
+	return &result, nil
+}
+
+// parsePutObjectResult extracts the result of an upload from the response headers.
+func parsePutObjectResult(resp *http.Response) PutObjectResult {
+	return PutObjectResult{
+		ETag:                 trimETag(resp.Header.Get("ETag")),
+		VersionId:            resp.Header.Get("x-amz-version-id"),
+		ServerSideEncryption: resp.Header.Get("x-amz-server-side-encryption"),
+		Checksum:             parseChecksumHeaders(resp.Header),
+	}
+}
+
+// PutObjectIfChanged uploads an object unless an object with the same content
+// already exists under the key, and reports whether an upload took place.
+// The content is compared by MD5 against the stored ETag, so objects with
+// multipart or SSE-KMS ETags are always uploaded again.
+func (c *Client) PutObjectIfChanged(ctx context.Context, bucketName, objectName string, data []byte, opts ...ObjectOption) (bool, error) {
+	existing, err := c.HeadObjectInfo(ctx, bucketName, objectName, opts...)
+	if err != nil && !isNotFound(err) {
+		return false, err
+	}
+
+	if err == nil && existing.ContentLength == int64(len(data)) {
+		hash := md5.Sum(data)
+		if existing.ETag == hex.EncodeToString(hash[:]) {
+			return false, nil
+		}
+	}
+
+	if err := c.PutObject(ctx, bucketName, objectName, data, opts...); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AppendObject appends data to an existing object in an S3 Express One Zone directory bucket.
+// The offset has to match the current object size, otherwise ErrWriteOffsetConflict is returned.
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/directory-buckets-objects-append.html
+func (c *Client) AppendObject(ctx context.Context, bucketName, objectName string, data []byte, offset int64) (*AppendObjectResult, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid write offset %d", offset)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPut, bucketName, objectName, nil, data)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-amz-write-offset-bytes", strconv.FormatInt(offset, 10))
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	result := AppendObjectResult{
+		ETag:     trimETag(resp.Header.Get("ETag")),
+		Checksum: parseChecksumHeaders(resp.Header),
+	}
+	if size, err := strconv.ParseInt(resp.Header.Get("x-amz-object-size"), 10, 64); err == nil {
+		result.ObjectSize = size
+	} else {
+		// services not reporting the size leave the object at offset plus the appended data
+		result.ObjectSize = offset + int64(len(data))
+	}
+
+	return &result, nil
+}
+
+// parseChecksumHeaders extracts the x-amz-checksum-* response headers.
+func parseChecksumHeaders(header http.Header) Checksum {
+	return Checksum{
+		ChecksumCRC32:     header.Get("x-amz-checksum-crc32"),
+		ChecksumCRC32C:    header.Get("x-amz-checksum-crc32c"),
+		ChecksumCRC64NVME: header.Get("x-amz-checksum-crc64nvme"),
+		ChecksumSHA1:      header.Get("x-amz-checksum-sha1"),
+		ChecksumSHA256:    header.Get("x-amz-checksum-sha256"),
+		ChecksumType:      header.Get("x-amz-checksum-type"),
+	}
 }
 
 // PutObject uploads an object to the specified bucket.
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObject.html
 // PutObject uploads an object to the specified bucket.
-func (c *Client) PutObjectStream(ctx context.Context, bucketName, objectName string, data io.Reader, metadata *PutObjectMetadata) (*http.Response, error) {
+func (c *Client) PutObjectStream(ctx context.Context, bucketName, objectName string, data io.Reader, metadata *PutObjectMetadata, opts ...ObjectOption) (*http.Response, error) {
+	if metadata != nil && metadata.VerifyMD5 {
+		opts = append(slices.Clone(opts), WithContentMD5())
+	}
+	options := newObjectOptions(opts)
+	if metadata != nil && metadata.ChecksumAlgorithm != "" && options.checksumAlgorithm == "" {
+		options.checksumAlgorithm = metadata.ChecksumAlgorithm
+	}
+	var contentMD5 string
+	if options.contentMD5 {
+		var err error
+		if contentMD5, err = buildReaderContentHash(data); err != nil {
+			return nil, err
+		}
+	}
+	// locked uploads must carry an integrity check, Content-MD5 is unknown
+	// before the stream is read unless it is seekable
+	if options.objectLocked() && options.checksumAlgorithm == "" && contentMD5 == "" {
+		options.checksumAlgorithm = "CRC32"
+	}
+
+	// a checksum without a value is computed while streaming and sent as trailer
+	var trailer *trailingChecksumReader
+	if options.checksumAlgorithm != "" && options.checksum == "" {
+		if metadata == nil || metadata.ContentLength <= 0 {
+			return nil, fmt.Errorf("trailing checksum requires the content length")
+		}
+		var err error
+		trailer, err = newTrailingChecksumReader(data, metadata.ContentLength, options.checksumAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		data = trailer
+	}
+	if options.chunkSize > 0 {
+		data = newChunkReader(data, options.chunkSize)
+	}
+
 	req, err := c.newRequestStream(ctx, http.MethodPut, bucketName, objectName, nil, data)
 	if err != nil {
 		return nil, err
 	}
+	options.setHeaders(req)
 
 	if metadata != nil {
 		if metadata.ContentLength > 0 {
-			req.Header.Set("Content-Length", fmt.Sprintf("%d", metadata.ContentLength))
+			req.ContentLength = metadata.ContentLength
 		}
+		if metadata.ContentType != "" {
+			req.Header.Set("Content-Type", metadata.ContentType)
+		}
+		if metadata.ServerSideEncryption != "" {
+			req.Header.Set("x-amz-server-side-encryption", metadata.ServerSideEncryption)
+		}
+		if metadata.SSEKMSKeyId != "" {
+			req.Header.Set("x-amz-server-side-encryption-aws-kms-key-id", metadata.SSEKMSKeyId)
+		}
+		setUserMetadata(req, metadata.UserMetadata)
+		if metadata.StorageClass != "" {
+			req.Header.Set("x-amz-storage-class", metadata.StorageClass)
+		}
+		if len(metadata.Tags) > 0 {
+			req.Header.Set("x-amz-tagging", encodeTags(metadata.Tags))
+		}
+	}
+	if contentMD5 != "" {
+		req.Header.Set("Content-MD5", contentMD5)
+	}
+	if trailer != nil {
+		setTrailerHeaders(req, trailer, metadata.ContentLength)
 	}
 
 	resp, err := c.do(req)
@@ -338,20 +849,114 @@ func (c *Client) PutObjectStream(ctx context.Context, bucketName, objectName str
 	return resp, nil
 }
 
-//	Delete a single specified object.
-//
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObject.html
-func (c *Client) DeleteObject(ctx context.Context, bucketName, objectName string, versionId string) (*http.Response, error) {
+// UploadFromRequest streams the body of an incoming HTTP request into an object
+// with Upload, using the Content-Length and Content-Type of the request.
+// Bodies of unknown length or above the multipart threshold are uploaded in
+// parts, so only a single part is buffered at a time.
+func (c *Client) UploadFromRequest(ctx context.Context, bucketName, objectName string, r *http.Request, opts ...ObjectOption) (*PutObjectResult, error) {
+	var objectOpts []ObjectOption
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		objectOpts = append(objectOpts, WithContentType(contentType))
+	}
+	objectOpts = append(objectOpts, opts...)
 
-	query := make(map[string]string)
-	if versionId != "" {
-		query["versionId"] = versionId
+	return c.Upload(ctx, bucketName, objectName, r.Body, r.ContentLength, &MultipartOptions{ObjectOptions: objectOpts})
+}
+
+// CopyObject creates a copy of an object that is already stored in S3.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CopyObject.html
+func (c *Client) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, opts *CopyOptions) (*CopyObjectResult, error) {
+	var result CopyObjectResult
+
+	req, err := c.newRequest(ctx, http.MethodPut, dstBucket, dstKey, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	source := copySource(srcBucket, srcKey)
+	if opts != nil && opts.SourceVersionId != "" {
+		source += "?versionId=" + url.QueryEscape(opts.SourceVersionId)
+	}
+	req.Header.Set("x-amz-copy-source", source)
+	if opts != nil {
+		newObjectOptions(opts.ObjectOptions).setHeaders(req)
+		if opts.IfNoneMatch != "" {
+			req.Header.Set("If-None-Match", opts.IfNoneMatch)
+		}
+		if opts.MetadataDirective != "" {
+			req.Header.Set("x-amz-metadata-directive", opts.MetadataDirective)
+		}
+		if opts.ContentType != "" {
+			req.Header.Set("Content-Type", opts.ContentType)
+		}
+		if opts.SourceSSECustomerKey != nil {
+			setSSECustomerHeaders(req, "x-amz-copy-source-server-side-encryption-customer-", opts.SourceSSECustomerKey)
+		}
+		if opts.SSECustomerKey != nil {
+			setSSECustomerHeaders(req, "x-amz-server-side-encryption-customer-", opts.SSECustomerKey)
+		}
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// a copy can fail after the 200 status has been sent, the body then holds the error
+	var errorResponse ErrorResponse
+	if unmarshalXML(data, &errorResponse) == nil && errorResponse.Code != "" {
+		errorResponse.StatusCode = resp.StatusCode
+		return nil, errorResponse
+	}
+
+	if err := unmarshalXML(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	result.ETag = trimETag(result.ETag)
+	result.VersionId = resp.Header.Get("x-amz-version-id")
+	result.CopySourceVersionId = resp.Header.Get("x-amz-copy-source-version-id")
+
+	return &result, nil
+}
+
+// copySource returns the value of the x-amz-copy-source header for an object,
+// with every key segment encoded like the canonical URI of the signature.
+func copySource(bucketName, objectName string) string {
+	segments := strings.Split(objectName, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment, true)
 	}
+	return "/" + bucketName + "/" + strings.Join(segments, "/")
+}
+
+// RestoreVersion makes an old version of an object the current one by copying
+// it onto the same key and returns the version id of the new current version.
+func (c *Client) RestoreVersion(ctx context.Context, bucketName, objectName, versionId string) (string, error) {
+	if versionId == "" {
+		return "", fmt.Errorf("version id must not be empty")
+	}
+	result, err := c.CopyObject(ctx, bucketName, objectName, bucketName, objectName, &CopyOptions{SourceVersionId: versionId})
+	if err != nil {
+		return "", err
+	}
+	return result.VersionId, nil
+}
 
-	req, err := c.newRequest(ctx, http.MethodDelete, bucketName, objectName, query, nil)
+//	Delete a single specified object.
+//
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObject.html
+func (c *Client) DeleteObject(ctx context.Context, bucketName, objectName string, opts ...ObjectOption) (*http.Response, error) {
+	options := newObjectOptions(opts)
+	req, err := c.newRequest(ctx, http.MethodDelete, bucketName, objectName, options.query(), nil)
 	if err != nil {
 		return nil, err
 	}
+	options.setHeaders(req)
 
 	resp, err := c.do(req)
 	if err != nil {
@@ -361,9 +966,25 @@ func (c *Client) DeleteObject(ctx context.Context, bucketName, objectName string
 	return resp, nil
 }
 
-// Delete multiple objects in a single request
+// Delete multiple objects, in batches of at most 1000 keys per request.
+// The results of all batches are combined. With Quiet set only the keys that
+// failed to be deleted are reported.
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObjects.html
 func (c *Client) DeleteObjects(ctx context.Context, bucketName string, objects Delete) (*DeleteResult, error) {
+	var result DeleteResult
+	for batch := range slices.Chunk(objects.Objects, maxDeleteObjects) {
+		batchResult, err := c.deleteObjects(ctx, bucketName, Delete{Objects: batch, Quiet: objects.Quiet})
+		if err != nil {
+			return nil, err
+		}
+		result.Deleted = append(result.Deleted, batchResult.Deleted...)
+		result.Errors = append(result.Errors, batchResult.Errors...)
+	}
+	return &result, nil
+}
+
+// deleteObjects deletes up to 1000 objects in a single request.
+func (c *Client) deleteObjects(ctx context.Context, bucketName string, objects Delete) (*DeleteResult, error) {
 	var deletionResponse DeleteResult
 
 	query := make(map[string]string)
@@ -374,7 +995,7 @@ func (c *Client) DeleteObjects(ctx context.Context, bucketName string, objects D
 		return nil, err
 	}
 
-	req, err := c.newRequest(ctx, http.MethodDelete, bucketName, "", query, data)
+	req, err := c.newRequest(ctx, http.MethodPost, bucketName, "", query, data)
 	if err != nil {
 		return nil, err
 	}
@@ -385,10 +1006,25 @@ func (c *Client) DeleteObjects(ctx context.Context, bucketName string, objects D
 	}
 	req.Header.Set("Content-MD5", hash)
 
-	_, err = c.do(req)
+	if algorithm := c.deleteChecksumAlgorithm(); algorithm != "MD5" {
+		checksum, err := ComputeChecksum(algorithm, data)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-amz-sdk-checksum-algorithm", algorithm)
+		req.Header.Set("x-amz-checksum-"+strings.ToLower(algorithm), checksum)
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
+	// keys that could not be deleted are reported as Error elements of a successful response
+	if err := newXMLDecoder(resp.Body).Decode(&deletionResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
 
 	return &deletionResponse, nil
 }
@@ -397,7 +1033,7 @@ func (c *Client) DeleteObjects(ctx context.Context, bucketName string, objects D
 
 // Initiate Multipart Upload and receive the uploadId
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateMultipartUpload.html
-func (c *Client) CreateMultipartUpload(ctx context.Context, bucketName string, filePath string) (*InitiateMultipartUploadResult, error) {
+func (c *Client) CreateMultipartUpload(ctx context.Context, bucketName string, filePath string, opts ...ObjectOption) (*InitiateMultipartUploadResult, error) {
 
 	var uploadData InitiateMultipartUploadResult
 
@@ -408,56 +1044,225 @@ func (c *Client) CreateMultipartUpload(ctx context.Context, bucketName string, f
 	if err != nil {
 		return nil, err
 	}
+	newObjectOptions(opts).setHeaders(req)
 
 	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	err = xml.NewDecoder(resp.Body).Decode(&uploadData)
+	defer resp.Body.Close()
+
+	err = newXMLDecoder(resp.Body).Decode(&uploadData)
 	if err != nil {
 		return nil, err
 	}
-
-	resp.Body.Close()
+	if uploadData.UploadId == "" {
+		return nil, fmt.Errorf("failed to create multipart upload: response contains no upload id")
+	}
 
 	return &uploadData, nil
 }
 
-// Upload a part
+// Upload a part. Of the options only WithChecksum, WithChunkSize,
+// WithSSECustomerKey and WithRequestPayer apply. WithChecksum without a value
+// computes the checksum while the part is sent, the upload must have been
+// created with the same algorithm. A part of an upload created with an SSE-C
+// key must be sent with the same key. The returned part carries the ETag and
+// the checksum as needed by CompleteMultipartUpload.
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_UploadPart.html
-func (c *Client) UploadPart(ctx context.Context, bucketName string, objectName string, data io.Reader, size uint64, partNumber uint64, uploadId string) (string, error) {
+func (c *Client) UploadPart(ctx context.Context, bucketName string, objectName string, data io.Reader, size uint64, partNumber uint64, uploadId string, opts ...ObjectOption) (*CompletedPart, error) {
+	if err := checkPartNumber(int64(partNumber)); err != nil {
+		return nil, err
+	}
+	// only the single part of an empty object may be empty
+	if size == 0 && partNumber > 1 {
+		return nil, fmt.Errorf("part %d is empty", partNumber)
+	}
 
 	query := make(map[string]string)
 	query["partNumber"] = strconv.FormatUint(uint64(partNumber), 10)
 	query["uploadId"] = uploadId
 
+	options := newObjectOptions(opts)
+	var contentMD5 string
+	if options.contentMD5 {
+		var err error
+		if contentMD5, err = buildReaderContentHash(data); err != nil {
+			return nil, err
+		}
+	}
+	var trailer *trailingChecksumReader
+	if options.checksumAlgorithm != "" && options.checksum == "" {
+		var err error
+		trailer, err = newTrailingChecksumReader(data, int64(size), options.checksumAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		data = trailer
+	}
+	if options.chunkSize > 0 {
+		data = newChunkReader(data, options.chunkSize)
+	}
 	req, err := c.newRequestStream(ctx, http.MethodPut, bucketName, objectName, query, data)
-	if err != nil && err != io.EOF {
-		return "Error streaming chunks", err
+	if err != nil {
+		return nil, err
+	}
+	options.setPartHeaders(req)
+	// the transport only sends a Content-Length set on the request itself,
+	// without it the part would be sent chunked and rejected
+	req.ContentLength = int64(size)
+	if contentMD5 != "" {
+		req.Header.Set("Content-MD5", contentMD5)
+	}
+	if trailer != nil {
+		setTrailerHeaders(req, trailer, int64(size))
+	} else if options.checksumAlgorithm != "" {
+		req.Header.Set("x-amz-checksum-"+strings.ToLower(options.checksumAlgorithm), options.checksum)
 	}
 
-	req.Header.Set("Content-Length", fmt.Sprintf("%d", size))
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	part := CompletedPart{
+		PartNumber: int(partNumber),
+		ETag:       trimETag(resp.Header.Get("ETag")),
+	}
+	if trailer != nil {
+		setPartChecksum(&part, options.checksumAlgorithm, trailer.checksum())
+	} else if options.checksumAlgorithm != "" {
+		setPartChecksum(&part, options.checksumAlgorithm, options.checksum)
+	}
+	return &part, nil
+}
+
+// Upload a part by copying a byte range of an existing object
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_UploadPartCopy.html
+func (c *Client) UploadPartCopy(ctx context.Context, dstBucket, dstKey, uploadId string, partNumber int, srcBucket, srcKey string, rangeStart, rangeEnd int64) (*CopyPartResult, error) {
+	if err := checkPartNumber(int64(partNumber)); err != nil {
+		return nil, err
+	}
+
+	var result CopyPartResult
+
+	query := make(map[string]string)
+	query["partNumber"] = strconv.Itoa(partNumber)
+	query["uploadId"] = uploadId
+
+	req, err := c.newRequest(ctx, http.MethodPut, dstBucket, dstKey, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-amz-copy-source", copySource(srcBucket, srcKey))
+	req.Header.Set("x-amz-copy-source-range", fmt.Sprintf("bytes=%d-%d", rangeStart, rangeEnd))
 
 	resp, err := c.do(req)
-	if err != nil && err != io.EOF {
-		return "", err
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// like CopyObject, the copy can fail after the 200 status has been sent
+	var errorResponse ErrorResponse
+	if unmarshalXML(data, &errorResponse) == nil && errorResponse.Code != "" {
+		errorResponse.StatusCode = resp.StatusCode
+		return nil, errorResponse
+	}
+
+	if err := unmarshalXML(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	result.ETag = trimETag(result.ETag)
+
+	return &result, nil
+}
+
+// Upload a part together with its checksum computed with the given algorithm
+// (CRC32, CRC32C, CRC64NVME, SHA1 or SHA256). The upload must have been created
+// with WithChecksum(algorithm, ""). The returned part carries the ETag and the
+// checksum as needed by CompleteMultipartUpload. Of the options only
+// WithSSECustomerKey and WithRequestPayer apply.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_UploadPart.html
+func (c *Client) UploadPartWithChecksum(ctx context.Context, bucketName string, objectName string, data []byte, partNumber int, uploadId string, algorithm string, opts ...ObjectOption) (*CompletedPart, error) {
+	if err := checkPartNumber(int64(partNumber)); err != nil {
+		return nil, err
+	}
+	checksum, err := ComputeChecksum(algorithm, data)
+	if err != nil {
+		return nil, err
+	}
+
+	query := make(map[string]string)
+	query["partNumber"] = strconv.Itoa(partNumber)
+	query["uploadId"] = uploadId
+
+	req, err := c.newRequest(ctx, http.MethodPut, bucketName, objectName, query, data)
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("x-amz-checksum-"+strings.ToLower(algorithm), checksum)
+	newObjectOptions(opts).setPartHeaders(req)
 
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
 	resp.Body.Close()
 
-	return resp.Header.Get("ETag"), nil
+	part := CompletedPart{
+		PartNumber: partNumber,
+		ETag:       trimETag(resp.Header.Get("ETag")),
+	}
+	setPartChecksum(&part, algorithm, checksum)
+	return &part, nil
 }
 
 // Complete the upload
+// Of the options only WithIfMatch and WithIfNoneMatch apply, WithIfNoneMatch("*")
+// fails the upload with ErrPreconditionFailed if the object already exists.
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_CompleteMultipartUpload.html
-func (c *Client) CompleteMultipartUpload(ctx context.Context, bucketName string, objectName string, uploadId string, parts []CompletedPart) error {
+func (c *Client) CompleteMultipartUpload(ctx context.Context, bucketName string, objectName string, uploadId string, parts []CompletedPart, opts ...ObjectOption) (*CompleteMultipartUploadResult, error) {
+	return c.completeMultipartUpload(ctx, bucketName, objectName, uploadId, parts, "", opts)
+}
+
+// Complete an upload created with the CRC64NVME checksum algorithm, passing the
+// CRC64NVME checksum of the full object. The upload fails if the checksum
+// computed by the service differs.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CompleteMultipartUpload.html
+func (c *Client) CompleteMultipartUploadWithChecksum(ctx context.Context, bucketName string, objectName string, uploadId string, parts []CompletedPart, crc64nvme string, opts ...ObjectOption) (*CompleteMultipartUploadResult, error) {
+	result, err := c.completeMultipartUpload(ctx, bucketName, objectName, uploadId, parts, crc64nvme, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.ChecksumCRC64NVME != "" && result.ChecksumCRC64NVME != crc64nvme {
+		return nil, fmt.Errorf("checksum mismatch: expected CRC64NVME %s, got %s", crc64nvme, result.ChecksumCRC64NVME)
+	}
+
+	return result, nil
+}
+
+func (c *Client) completeMultipartUpload(ctx context.Context, bucketName string, objectName string, uploadId string, parts []CompletedPart, crc64nvme string, opts []ObjectOption) (*CompleteMultipartUploadResult, error) {
+	var result CompleteMultipartUploadResult
 
 	query := make(map[string]string)
 	query["uploadId"] = string(uploadId)
 
+	// the parts are sent with quoted ETags, as they were returned by the service
 	completeUpload := CompleteMultipartUpload{
-		Parts: parts,
+		Parts: make([]CompletedPart, len(parts)),
+	}
+	for i, part := range parts {
+		part.ETag = quoteETag(part.ETag)
+		completeUpload.Parts[i] = part
 	}
 	xmlData, err := xml.Marshal(completeUpload)
 	if err != nil {
@@ -466,16 +1271,91 @@ func (c *Client) CompleteMultipartUpload(ctx context.Context, bucketName string,
 
 	endReq, err := c.newRequestStream(ctx, http.MethodPost, bucketName, objectName, query, bytes.NewReader(xmlData))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	endReq.Header.Set("Content-Type", "application/xml")
+	newObjectOptions(opts).setConditionalHeaders(endReq)
+	if crc64nvme != "" {
+		endReq.Header.Set("x-amz-checksum-crc64nvme", crc64nvme)
+		endReq.Header.Set("x-amz-checksum-type", "FULL_OBJECT")
+	}
 
-	_, err = c.do(endReq)
+	resp, err := c.do(endReq)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// the upload can fail after the 200 status has been sent, the body then holds the error
+	var errorResponse ErrorResponse
+	if unmarshalXML(data, &errorResponse) == nil && errorResponse.Code != "" {
+		errorResponse.StatusCode = resp.StatusCode
+		return nil, errorResponse
+	}
+
+	// a successful upload always returns the result element
+	if err := unmarshalXML(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	result.ETag = trimETag(result.ETag)
+	result.VersionId = resp.Header.Get("x-amz-version-id")
+
+	return &result, nil
+}
+
+// Complete the upload with parts as returned by ListParts.
+// The parts are ordered by number and must form a contiguous sequence starting at 1,
+// so an upload missing a part in between is rejected before it is submitted.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CompleteMultipartUpload.html
+func (c *Client) CompleteMultipartUploadWithParts(ctx context.Context, bucketName string, objectName string, uploadId string, parts []Part, opts ...ObjectOption) (*CompleteMultipartUploadResult, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no parts to complete upload %s", uploadId)
+	}
+
+	sorted := slices.Clone(parts)
+	slices.SortFunc(sorted, func(a, b Part) int {
+		return a.PartNumber - b.PartNumber
+	})
+
+	completed := make([]CompletedPart, 0, len(sorted))
+	for i, part := range sorted {
+		if part.PartNumber != i+1 {
+			return nil, fmt.Errorf("parts are not contiguous: expected part %d, got part %d", i+1, part.PartNumber)
+		}
+		completed = append(completed, part.completedPart())
+	}
+
+	return c.CompleteMultipartUpload(ctx, bucketName, objectName, uploadId, completed, opts...)
+}
+
+// Complete the upload with all parts the service has stored for it, as listed by
+// ListParts. This allows parts to be uploaded by independent workers without
+// reporting their ETags back to the caller completing the upload.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListParts.html
+func (c *Client) CompleteFromListedParts(ctx context.Context, bucketName string, objectName string, uploadId string) error {
+	var parts []Part
+
+	query := make(map[string]string)
+	for {
+		result, err := c.ListParts(ctx, bucketName, objectName, uploadId, query)
+		if err != nil {
+			return fmt.Errorf("failed to list parts: %w", err)
+		}
+		parts = append(parts, result.Parts...)
+
+		if !result.IsTruncated {
+			break
+		}
+		query["part-number-marker"] = strconv.Itoa(result.NextPartNumberMarker)
+	}
+
+	_, err := c.CompleteMultipartUploadWithParts(ctx, bucketName, objectName, uploadId, parts)
+	return err
 }
 
 // lists in-progress multipart uploads within a bucket
@@ -501,14 +1381,17 @@ func (c *Client) ListMultipartUploads(ctx context.Context, bucketName string, qu
 	defer resp.Body.Close()
 
 	data, err := io.ReadAll(resp.Body)
-	if err != nil && err != io.EOF {
+	if err != nil {
 		return nil, err
 	}
 
-	err = xml.Unmarshal(data, &listPartsResult)
+	err = unmarshalXML(data, &listPartsResult)
 	if err != nil {
 		return nil, err
 	}
+	if err := listPartsResult.decodeURLEncoding(); err != nil {
+		return nil, err
+	}
 
 	resp.Body.Close()
 
@@ -527,10 +1410,11 @@ func (c *Client) AbortMultipartUpload(ctx context.Context, bucketName string, fi
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -557,10 +1441,13 @@ func (c *Client) ListParts(ctx context.Context, bucketName string, filePath stri
 	}
 	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&listPartsResult)
+	err = newXMLDecoder(resp.Body).Decode(&listPartsResult)
 	if err != nil {
 		return nil, err
 	}
+	for i := range listPartsResult.Parts {
+		listPartsResult.Parts[i].ETag = trimETag(listPartsResult.Parts[i].ETag)
+	}
 
 	resp.Body.Close()
 
@@ -590,8 +1477,9 @@ func (c *Client) GetObjectTagging(ctx context.Context, bucketName string, filePa
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&attributes)
+	err = newXMLDecoder(resp.Body).Decode(&attributes)
 	if err != nil {
 		fmt.Println("Error parsing XML:", err)
 		return nil, err
@@ -649,14 +1537,18 @@ func (c *Client) DeleteObjectTagging(ctx context.Context, bucketName string, fil
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
 
+// all attributes requested from GetObjectAttributes
+const objectAttributes = "ETag,Checksum,ObjectParts,StorageClass,ObjectSize"
+
 // Retrieve object metadata
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectAttributes.html
 func (c *Client) GetObjectAttributes(ctx context.Context, bucketName string, filePath string, query map[string]string) (*GetObjectAttributesResponse, error) {
@@ -672,6 +1564,35 @@ func (c *Client) GetObjectAttributes(ctx context.Context, bucketName string, fil
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("x-amz-object-attributes", objectAttributes)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = newXMLDecoder(resp.Body).Decode(&attributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w: %w", errNoAttributesDocument, err)
+	}
+	attributes.VersionId = resp.Header.Get("x-amz-version-id")
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		attributes.LastModified = lastModified
+	}
+
+	return &attributes, nil
+}
+
+// List all buckets
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListDirectoryBuckets.html
+func (c *Client) ListDirectoryBuckets(ctx context.Context, query map[string]string) (*ListAllMyDirectoryBucketsResult, error) {
+	var list ListAllMyDirectoryBucketsResult
+
+	req, err := c.newRequest(ctx, http.MethodGet, "", "", query, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	resp, err := c.do(req)
 	if err != nil {
@@ -679,52 +1600,107 @@ func (c *Client) GetObjectAttributes(ctx context.Context, bucketName string, fil
 	}
 	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&attributes)
+	err = newXMLDecoder(resp.Body).Decode(&list)
 	if err != nil {
 		fmt.Println("Error parsing XML:", err)
 		return nil, err
 	}
-
+
+	resp.Body.Close()
+
+	return &list, err
+}
+
+// Website
+
+// Retrieve bucket website configuration
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketWebsite.html
+func (c *Client) GetBucketWebsite(ctx context.Context, bucketName string) (*WebsiteConfiguration, error) {
+	var config WebsiteConfiguration
+	query := make(map[string]string)
+	query["website"] = ""
+
+	req, err := c.newRequest(ctx, http.MethodGet, bucketName, "", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = newXMLDecoder(resp.Body).Decode(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	return &config, nil
+}
+
+// Put bucket website configuration
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketWebsite.html
+func (c *Client) PutBucketWebsite(ctx context.Context, bucketName string, config WebsiteConfiguration) error {
+	query := make(map[string]string)
+	query["website"] = ""
+
+	data, err := xml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPut, bucketName, "", nil, data)
+	if err != nil {
+		return err
+	}
+
+	hash, err := buildContentHash(data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-MD5", hash)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
 	resp.Body.Close()
 
-	return &attributes, nil
+	return nil
 }
 
-// List all buckets
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListDirectoryBuckets.html
-func (c *Client) ListDirectoryBuckets(ctx context.Context, query map[string]string) (*ListAllMyDirectoryBucketsResult, error) {
-	var list ListAllMyDirectoryBucketsResult
+// Delete bucket website
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketWebsite.html
+func (c *Client) DeleteBucketWebsite(ctx context.Context, bucketName string) error {
+	query := make(map[string]string)
+	query["website"] = ""
 
-	req, err := c.newRequest(ctx, http.MethodGet, "", "", query, nil)
+	req, err := c.newRequest(ctx, http.MethodDelete, bucketName, "", query, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	resp, err := c.do(req)
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	err = xml.NewDecoder(resp.Body).Decode(&list)
-	if err != nil {
-		fmt.Println("Error parsing XML:", err)
-		return nil, err
+		return err
 	}
-
 	resp.Body.Close()
 
-	return &list, err
+	return nil
+
 }
 
-// Website
+// CORS
 
-// Retrieve bucket website configuration
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketWebsite.html
-func (c *Client) GetBucketWebsite(ctx context.Context, bucketName string) (*WebsiteConfiguration, error) {
-	var config WebsiteConfiguration
+// Retrieve bucket CORS configuration
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketCors.html
+func (c *Client) GetBucketCors(ctx context.Context, bucketName string) (*CORSConfiguration, error) {
+	var config CORSConfiguration
 	query := make(map[string]string)
-	query["website"] = ""
+	query["cors"] = ""
 
 	req, err := c.newRequest(ctx, http.MethodGet, bucketName, "", query, nil)
 	if err != nil {
@@ -737,28 +1713,26 @@ func (c *Client) GetBucketWebsite(ctx context.Context, bucketName string) (*Webs
 	}
 	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&config)
+	err = newXMLDecoder(resp.Body).Decode(&config)
 	if err != nil {
 		return nil, err
 	}
 
-	resp.Body.Close()
-
 	return &config, nil
 }
 
-// Put bucket website configuration
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketWebsite.html
-func (c *Client) PutBucketWebsite(ctx context.Context, bucketName string, config WebsiteConfiguration) error {
+// Put bucket CORS configuration
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketCors.html
+func (c *Client) PutBucketCors(ctx context.Context, bucketName string, config CORSConfiguration) error {
 	query := make(map[string]string)
-	query["website"] = ""
+	query["cors"] = ""
 
 	data, err := xml.Marshal(config)
 	if err != nil {
 		return err
 	}
 
-	req, err := c.newRequest(ctx, http.MethodPut, bucketName, "", nil, data)
+	req, err := c.newRequest(ctx, http.MethodPut, bucketName, "", query, data)
 	if err != nil {
 		return err
 	}
@@ -769,32 +1743,33 @@ func (c *Client) PutBucketWebsite(ctx context.Context, bucketName string, config
 	}
 	req.Header.Set("Content-MD5", hash)
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
 
-// Delete bucket website
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketWebsite.html
-func (c *Client) DeleteBucketWebsite(ctx context.Context, bucketName string) error {
+// Delete bucket CORS configuration
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketCors.html
+func (c *Client) DeleteBucketCors(ctx context.Context, bucketName string) error {
 	query := make(map[string]string)
-	query["website"] = ""
+	query["cors"] = ""
 
 	req, err := c.newRequest(ctx, http.MethodDelete, bucketName, "", query, nil)
 	if err != nil {
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
-
 }
 
 // Bucket Versioning
@@ -815,8 +1790,9 @@ func (c *Client) GetBucketVersioning(ctx context.Context, bucketName string) (*V
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&config)
+	err = newXMLDecoder(resp.Body).Decode(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -846,14 +1822,112 @@ func (c *Client) PutBucketVersioning(ctx context.Context, bucketName string, ver
 	}
 	req.Header.Set("Content-MD5", hash)
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Bucket Encryption
+
+// Get the default encryption configuration of a bucket
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketEncryption.html
+func (c *Client) GetBucketEncryption(ctx context.Context, bucketName string) (*ServerSideEncryptionConfiguration, error) {
+	var config ServerSideEncryptionConfiguration
+	query := make(map[string]string)
+	query["encryption"] = ""
+
+	req, err := c.newRequest(ctx, http.MethodGet, bucketName, "", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = newXMLDecoder(resp.Body).Decode(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Put the default encryption configuration of a bucket
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketEncryption.html
+func (c *Client) PutBucketEncryption(ctx context.Context, bucketName string, config ServerSideEncryptionConfiguration) error {
+	query := make(map[string]string)
+	query["encryption"] = ""
+
+	data, err := xml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPut, bucketName, "", query, data)
+	if err != nil {
+		return err
+	}
+
+	hash, err := buildContentHash(data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-MD5", hash)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Delete the default encryption configuration of a bucket, restoring the
+// service default (SSE-S3)
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketEncryption.html
+func (c *Client) DeleteBucketEncryption(ctx context.Context, bucketName string) error {
+	query := make(map[string]string)
+	query["encryption"] = ""
+
+	req, err := c.newRequest(ctx, http.MethodDelete, bucketName, "", query, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
 
+// RequireEncryption returns an error matching ErrNoDefaultEncryption unless
+// the bucket has a default server-side encryption rule, so sensitive data
+// can be refused before it is written to an unencrypted bucket.
+func (c *Client) RequireEncryption(ctx context.Context, bucketName string) error {
+	config, err := c.GetBucketEncryption(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range config.Rules {
+		if rule.ApplyServerSideEncryptionByDefault != nil && rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("bucket %s: %w", bucketName, ErrNoDefaultEncryption)
+}
+
 // Bucket Tagging
 
 // get bucket tagigng
@@ -872,8 +1946,9 @@ func (c *Client) GetBucketTagging(ctx context.Context, bucketName string) (*Tagg
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&tagging)
+	err = newXMLDecoder(resp.Body).Decode(&tagging)
 	if err != nil {
 		return nil, err
 	}
@@ -923,10 +1998,11 @@ func (c *Client) DeleteBucketTagging(ctx context.Context, bucketName string) err
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 
@@ -956,10 +2032,11 @@ func (c *Client) PutObjectLockConfiguration(ctx context.Context, bucketName stri
 	}
 	req.Header.Set("Content-MD5", hash)
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -980,8 +2057,9 @@ func (c *Client) GetObjectLockConfiguration(ctx context.Context, bucketName stri
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&config)
+	err = newXMLDecoder(resp.Body).Decode(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -1008,8 +2086,9 @@ func (c *Client) GetObjectRetention(ctx context.Context, bucketName string, file
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&retention)
+	err = newXMLDecoder(resp.Body).Decode(&retention)
 	if err != nil {
 		return nil, err
 	}
@@ -1039,10 +2118,11 @@ func (c *Client) PutObjectRetention(ctx context.Context, bucketName string, file
 	}
 	req.Header.Set("Content-MD5", hash)
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1067,8 +2147,9 @@ func (c *Client) GetObjectAcl(ctx context.Context, bucketName string, filePath s
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&policy)
+	err = newXMLDecoder(resp.Body).Decode(&policy)
 	if err != nil {
 		return nil, err
 	}
@@ -1102,6 +2183,7 @@ func (c *Client) PutObjectAcl(ctx context.Context, bucketName string, filePath s
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
 
 	return resp.Header.Get("x-amz-request-charged"), nil
 }
@@ -1124,8 +2206,9 @@ func (c *Client) GetBucketAcl(ctx context.Context, bucketName string, filePath s
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&policy)
+	err = newXMLDecoder(resp.Body).Decode(&policy)
 	if err != nil {
 		return nil, err
 	}
@@ -1149,10 +2232,11 @@ func (c *Client) PutBucketAcl(ctx context.Context, bucketName string, policy Acc
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1175,8 +2259,9 @@ func (c *Client) GetBucketLogging(ctx context.Context, bucketName string) (*Buck
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&config)
+	err = newXMLDecoder(resp.Body).Decode(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -1200,10 +2285,11 @@ func (c *Client) PutBucketLogging(ctx context.Context, bucketName string, config
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1226,8 +2312,9 @@ func (c *Client) GetPublicAccessBlock(ctx context.Context, bucketName string) (*
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&config)
+	err = newXMLDecoder(resp.Body).Decode(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -1257,10 +2344,11 @@ func (c *Client) PutPublicAccessBlock(ctx context.Context, bucketName string, co
 	}
 	req.Header.Set("Content-MD5", hash)
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1276,10 +2364,11 @@ func (c *Client) DeletePublicAccessBlock(ctx context.Context, bucketName string)
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1302,8 +2391,9 @@ func (c *Client) GetBucketNotificationConfiguration(ctx context.Context, bucketN
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&config)
+	err = newXMLDecoder(resp.Body).Decode(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -1326,10 +2416,11 @@ func (c *Client) PutBucketNotificationConfiguration(ctx context.Context, bucketN
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1353,8 +2444,9 @@ func (c *Client) GetBucketMetricsConfiguration(ctx context.Context, bucketName s
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&config)
+	err = newXMLDecoder(resp.Body).Decode(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -1382,8 +2474,9 @@ func (c *Client) ListBucketMetricsConfigurations(ctx context.Context, bucketName
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&config)
+	err = newXMLDecoder(resp.Body).Decode(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -1407,10 +2500,11 @@ func (c *Client) PutBucketMetricsConfiguration(ctx context.Context, bucketName s
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1428,10 +2522,11 @@ func (c *Client) DeleteBucketMetricsConfiguration(ctx context.Context, bucketNam
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1459,8 +2554,9 @@ func (c *Client) GetObjectLegalHold(ctx context.Context, bucketName string, file
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&hold)
+	err = newXMLDecoder(resp.Body).Decode(&hold)
 	if err != nil {
 		fmt.Println("Error parsing XML:", err)
 		return nil, err
@@ -1495,10 +2591,11 @@ func (c *Client) PutObjectLegalHold(ctx context.Context, bucketName string, file
 	}
 	req.Header.Set("Content-MD5", hash)
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1521,8 +2618,9 @@ func (c *Client) GetBucketPolicyStatus(ctx context.Context, bucketName string) (
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&policyStatus)
+	err = newXMLDecoder(resp.Body).Decode(&policyStatus)
 	if err != nil {
 		return nil, err
 	}
@@ -1546,8 +2644,9 @@ func (c *Client) GetBucketPolicy(ctx context.Context, bucketName string) (*Bucke
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&policy)
+	err = newXMLDecoder(resp.Body).Decode(&policy)
 	if err != nil {
 		return nil, err
 	}
@@ -1577,10 +2676,11 @@ func (c *Client) PutBucketPolicy(ctx context.Context, bucketName string, policy
 	}
 	req.Header.Set("Content-MD5", hash)
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1596,10 +2696,11 @@ func (c *Client) DeleteBucketPolicy(ctx context.Context, bucketName string) erro
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1620,8 +2721,9 @@ func (c *Client) GetBucketLifecycleConfiguration(ctx context.Context, bucketName
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&config)
+	err = newXMLDecoder(resp.Body).Decode(&config)
 	if err != nil {
 		return nil, err
 	}
@@ -1655,6 +2757,7 @@ func (c *Client) PutBucketLifecycleConfiguration(ctx context.Context, bucketName
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
 
 	return resp.Header.Get("x-amz-transition-default-minimum-object-size"), nil
 }
@@ -1670,10 +2773,90 @@ func (c *Client) DeleteBucketLifecycle(ctx context.Context, bucketName string) e
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Bucket Replication
+
+// Get bucket replication configuration
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketReplication.html
+func (c *Client) GetBucketReplication(ctx context.Context, bucketName string) (*ReplicationConfiguration, error) {
+	var config ReplicationConfiguration
+	query := make(map[string]string)
+	query["replication"] = ""
+
+	req, err := c.newRequest(ctx, http.MethodGet, bucketName, "", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = newXMLDecoder(resp.Body).Decode(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Put bucket replication configuration
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketReplication.html
+func (c *Client) PutBucketReplication(ctx context.Context, bucketName string, config ReplicationConfiguration) error {
+	query := make(map[string]string)
+	query["replication"] = ""
+
+	data, err := xml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPut, bucketName, "", query, data)
+	if err != nil {
+		return err
+	}
+
+	hash, err := buildContentHash(data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-MD5", hash)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Delete bucket replication configuration
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketReplication.html
+func (c *Client) DeleteBucketReplication(ctx context.Context, bucketName string) error {
+	query := make(map[string]string)
+	query["replication"] = ""
+
+	req, err := c.newRequest(ctx, http.MethodDelete, bucketName, "", query, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1697,8 +2880,9 @@ func (c *Client) GetBucketMetadataTableConfiguration(ctx context.Context, bucket
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	err = xml.NewDecoder(resp.Body).Decode(&metadata)
+	err = newXMLDecoder(resp.Body).Decode(&metadata)
 	if err != nil {
 		return nil, err
 	}
@@ -1729,10 +2913,11 @@ func (c *Client) CreateBucketMetadataTableConfiguration(ctx context.Context, buc
 	}
 	req.Header.Set("Content-MD5", hash)
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -1749,26 +2934,78 @@ func (c *Client) DeleteBucketMetadataTableConfiguration(ctx context.Context, buc
 		return err
 	}
 
-	_, err = c.do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 
 	return nil
 }
 
 // chunkReader wraps an io.Reader and provides a reader that returns data in chunks.
 type chunkReader struct {
-	src io.Reader
+	src  io.Reader
+	size int
 }
 
-func newChunkReader(src io.Reader) *chunkReader {
-	return &chunkReader{src: src}
+func newChunkReader(src io.Reader, size int) *chunkReader {
+	return &chunkReader{src: src, size: size}
 }
 
 func (cr *chunkReader) Read(p []byte) (n int, err error) {
-	if len(p) > chunkSize {
-		p = p[:chunkSize] // limit read to 4KB
+	if len(p) > cr.size {
+		p = p[:cr.size] // limit a single read to the chunk size
 	}
 	return cr.src.Read(p)
 }
+
+// deleteChecksumAlgorithm returns the configured checksum algorithm of
+// DeleteObjects requests or CRC32.
+func (c *Client) deleteChecksumAlgorithm() string {
+	if c.config.DeleteChecksumAlgorithm != "" {
+		return strings.ToUpper(c.config.DeleteChecksumAlgorithm)
+	}
+	return "CRC32"
+}
+
+// chunkSize returns the configured size of a single read from a streamed body.
+func (c *Client) chunkSize() int {
+	if c.config.ChunkSize > 0 {
+		return c.config.ChunkSize
+	}
+	return defaultChunkSize
+}
+
+// contextBody ties a response body to the context of its request. A done
+// context closes the body to unblock pending reads and fails further reads
+// with the context's error.
+type contextBody struct {
+	ctx  context.Context
+	body io.ReadCloser
+	stop func() bool
+}
+
+func newContextBody(ctx context.Context, body io.ReadCloser) *contextBody {
+	cb := &contextBody{ctx: ctx, body: body}
+	cb.stop = context.AfterFunc(ctx, func() {
+		body.Close()
+	})
+	return cb
+}
+
+func (cb *contextBody) Read(p []byte) (int, error) {
+	if err := cb.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := cb.body.Read(p)
+	if err != nil && cb.ctx.Err() != nil {
+		return n, cb.ctx.Err()
+	}
+	return n, err
+}
+
+func (cb *contextBody) Close() error {
+	cb.stop()
+	return cb.body.Close()
+}
@@ -0,0 +1,1134 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client that sends its requests to a test server
+// running handler, addressing buckets in the path.
+func newTestClient(t testing.TB, handler http.Handler) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := New(Config{
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Endpoint:  server.URL,
+		PathStyle: true,
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRequestContentLengthIsSetByTransport(t *testing.T) {
+	var got []int64
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Authorization"), "content-length") {
+			t.Errorf("Content-Length is signed: %s", r.Header.Get("Authorization"))
+		}
+		got = append(got, r.ContentLength)
+	}))
+
+	for _, body := range [][]byte{nil, []byte("hello")} {
+		req, err := client.newRequest(context.Background(), http.MethodPut, "bucket", "key", nil, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := req.Header.Get("Content-Length"); value != "" {
+			t.Errorf("Content-Length header set by hand: %q", value)
+		}
+		resp, err := client.do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if want := []int64{0, 5}; !slices.Equal(got, want) {
+		t.Errorf("got content lengths %v, want %v", got, want)
+	}
+}
+
+// requestRecorder records the last request it received and answers it with
+// response.
+type requestRecorder struct {
+	response string
+	method   string
+	query    url.Values
+	header   http.Header
+	body     string
+}
+
+func (r *requestRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.method, r.query, r.header, r.body = req.Method, req.URL.Query(), req.Header.Clone(), string(body)
+	fmt.Fprint(w, r.response)
+}
+
+func TestCopyObjectAppliesObjectOptions(t *testing.T) {
+	var header http.Header
+	var query string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header, query = r.Header.Clone(), r.URL.RawQuery
+		fmt.Fprint(w, `<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`)
+	}))
+
+	_, err := client.CopyObject(context.Background(), "src", "a.txt", "dst", "b.txt", &CopyOptions{
+		IfNoneMatch: "*",
+		ObjectOptions: []ObjectOption{
+			WithSSE("AES256", ""),
+			WithRequestPayer(),
+			WithVersionID("ignored"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"x-amz-copy-source":            "/src/a.txt",
+		"x-amz-server-side-encryption": "AES256",
+		"x-amz-request-payer":          "requester",
+		"If-None-Match":                "*",
+	}
+	for name, value := range want {
+		if got := header.Get(name); got != value {
+			t.Errorf("got %s %q, want %q", name, got, value)
+		}
+	}
+	if query != "" {
+		t.Errorf("got query %q on the destination", query)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper calling the function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransportErrorIsReturned(t *testing.T) {
+	errTransport := errors.New("connection refused")
+	httpClient := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errTransport
+	})}
+	client, err := New(Config{Region: "us-east-1", Endpoint: "https://s3.example.com", PathStyle: true}, httpClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	operations := map[string]func() error{
+		"CreateBucket": func() error { return client.CreateBucket(ctx, "bucket") },
+		"HeadObject": func() error {
+			_, err := client.HeadObject(ctx, "bucket", "key")
+			return err
+		},
+		"GetObject": func() error {
+			_, err := client.GetObject(ctx, "bucket", "key")
+			return err
+		},
+		"PutObject": func() error { return client.PutObject(ctx, "bucket", "key", []byte("data")) },
+		"CopyObject": func() error {
+			_, err := client.CopyObject(ctx, "bucket", "src", "bucket", "dst", nil)
+			return err
+		},
+		"DeleteObject": func() error {
+			_, err := client.DeleteObject(ctx, "bucket", "key")
+			return err
+		},
+	}
+	for name, operation := range operations {
+		t.Run(name, func(t *testing.T) {
+			if err := operation(); !errors.Is(err, errTransport) {
+				t.Errorf("got error %v, want %v", err, errTransport)
+			}
+		})
+	}
+}
+
+func TestDeleteObjectsSplitsIntoBatches(t *testing.T) {
+	var batches []int
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if want, _ := buildContentHash(body); r.Header.Get("Content-MD5") != want {
+			t.Errorf("got Content-MD5 %s, want %s", r.Header.Get("Content-MD5"), want)
+		}
+		var objects Delete
+		if err := xml.Unmarshal(body, &objects); err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, len(objects.Objects))
+
+		fmt.Fprint(w, `<DeleteResult>`)
+		for _, object := range objects.Objects {
+			fmt.Fprintf(w, `<Deleted><Key>%s</Key></Deleted>`, object.Key)
+		}
+		fmt.Fprint(w, `</DeleteResult>`)
+	}))
+
+	var objects Delete
+	for i := range 2500 {
+		objects.Objects = append(objects.Objects, ObjectIdentifier{Key: fmt.Sprintf("key-%d", i)})
+	}
+	result, err := client.DeleteObjects(context.Background(), "bucket", objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int{1000, 1000, 500}; !slices.Equal(batches, want) {
+		t.Errorf("got batches %v, want %v", batches, want)
+	}
+	if len(result.Deleted) != 2500 {
+		t.Errorf("got %d deleted keys, want 2500", len(result.Deleted))
+	}
+}
+
+func TestBuildEndpointAddressing(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoint  string
+		pathStyle bool
+		want      string
+	}{
+		{name: "virtual-hosted", endpoint: "https://s3.amazonaws.com", want: "https://bucket.s3.amazonaws.com/dir/key"},
+		{name: "path-style", endpoint: "https://minio.example.com:9000", pathStyle: true, want: "https://minio.example.com:9000/bucket/dir/key"},
+		{name: "virtual-hosted with port", endpoint: "http://s3.local:9000", want: "http://bucket.s3.local:9000/dir/key"},
+		{name: "IP address", endpoint: "http://127.0.0.1:9000", want: "http://127.0.0.1:9000/bucket/dir/key"},
+		{name: "localhost", endpoint: "http://localhost:9000", want: "http://localhost:9000/bucket/dir/key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := New(Config{Endpoint: tt.endpoint, PathStyle: tt.pathStyle}, http.DefaultClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := client.buildEndpoint("bucket", "dir/key", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadContentType(t *testing.T) {
+	recorder := &requestRecorder{}
+	client := newTestClient(t, recorder)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		upload func() error
+		want   string
+	}{
+		{
+			name:   "PutObject from extension",
+			upload: func() error { return client.PutObject(ctx, "bucket", "data.json", []byte("{}")) },
+			want:   "application/json",
+		},
+		{
+			name:   "PutObject without extension",
+			upload: func() error { return client.PutObject(ctx, "bucket", "data", []byte("{}")) },
+			want:   "application/octet-stream",
+		},
+		{
+			name: "PutObject with option",
+			upload: func() error {
+				return client.PutObject(ctx, "bucket", "data.json", []byte("{}"), WithContentType("text/plain"))
+			},
+			want: "text/plain",
+		},
+		{
+			name: "PutObjectStream from extension",
+			upload: func() error {
+				resp, err := client.PutObjectStream(ctx, "bucket", "index.html", strings.NewReader("<p>"), &PutObjectMetadata{ContentLength: 3})
+				if err != nil {
+					return err
+				}
+				return resp.Body.Close()
+			},
+			want: "text/html; charset=utf-8",
+		},
+		{
+			name: "PutObjectStream with metadata",
+			upload: func() error {
+				resp, err := client.PutObjectStream(ctx, "bucket", "index.html", strings.NewReader("<p>"), &PutObjectMetadata{ContentLength: 3, ContentType: "text/plain"})
+				if err != nil {
+					return err
+				}
+				return resp.Body.Close()
+			},
+			want: "text/plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.upload(); err != nil {
+				t.Fatal(err)
+			}
+			if got := recorder.header.Get("Content-Type"); got != tt.want {
+				t.Errorf("got Content-Type %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopySourceEncoding(t *testing.T) {
+	tests := map[string]string{
+		"plain.txt":        "/bucket/plain.txt",
+		"dir/a b.txt":      "/bucket/dir/a%20b.txt",
+		"a+b=c&d":          "/bucket/a%2Bb%3Dc%26d",
+		"ümlaut/(1)~_-.":   "/bucket/%C3%BCmlaut/%281%29~_-.",
+		"semi;colon:$@,!*": "/bucket/semi%3Bcolon%3A%24%40%2C%21%2A",
+	}
+	for key, want := range tests {
+		if got := copySource("bucket", key); got != want {
+			t.Errorf("copySource(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestUploadPartCopy(t *testing.T) {
+	recorder := &requestRecorder{response: `<CopyPartResult><ETag>"part-etag"</ETag><LastModified>2013-05-24T00:00:00Z</LastModified></CopyPartResult>`}
+	client := newTestClient(t, recorder)
+
+	result, err := client.UploadPartCopy(context.Background(), "dst", "big", "upload-1", 2, "src", "dir/a b", 5242880, 10485759)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ETag != "part-etag" {
+		t.Errorf("got ETag %q, want part-etag", result.ETag)
+	}
+	if recorder.query.Get("partNumber") != "2" || recorder.query.Get("uploadId") != "upload-1" {
+		t.Errorf("got query %v", recorder.query)
+	}
+	if got := recorder.header.Get("x-amz-copy-source"); got != "/src/dir/a%20b" {
+		t.Errorf("got x-amz-copy-source %q", got)
+	}
+	if got := recorder.header.Get("x-amz-copy-source-range"); got != "bytes=5242880-10485759" {
+		t.Errorf("got x-amz-copy-source-range %q", got)
+	}
+
+	// an error in the body of a 200 response fails the part
+	recorder.response = `<Error><Code>InternalError</Code><Message>We encountered an internal error.</Message></Error>`
+	_, err = client.UploadPartCopy(context.Background(), "dst", "big", "upload-1", 2, "src", "a", 0, 9)
+	var errorResponse ErrorResponse
+	if !errors.As(err, &errorResponse) || errorResponse.Code != "InternalError" {
+		t.Errorf("got error %v, want InternalError", err)
+	}
+}
+
+func TestUploadFromRequest(t *testing.T) {
+	t.Run("known length", func(t *testing.T) {
+		var header http.Header
+		var body string
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			header, body = r.Header.Clone(), string(data)
+			w.Header().Set("ETag", `"etag"`)
+		}))
+
+		r := httptest.NewRequest(http.MethodPut, "/upload", strings.NewReader("hello"))
+		r.Header.Set("Content-Type", "text/x-test")
+		result, err := client.UploadFromRequest(context.Background(), "bucket", "key", r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.ETag != "etag" || body != "hello" {
+			t.Errorf("got ETag %q and body %q", result.ETag, body)
+		}
+		if got := header.Get("Content-Type"); got != "text/x-test" {
+			t.Errorf("got Content-Type %q, want text/x-test", got)
+		}
+	})
+
+	t.Run("unknown length", func(t *testing.T) {
+		server := &multipartServer{partHeaders: make(map[string]http.Header)}
+		client := newTestClient(t, server)
+
+		r := httptest.NewRequest(http.MethodPut, "/upload", io.MultiReader(strings.NewReader("hello")))
+		r.ContentLength = -1
+		if _, err := client.UploadFromRequest(context.Background(), "bucket", "key", r); err != nil {
+			t.Fatal(err)
+		}
+		if len(server.partHeaders) != 1 {
+			t.Errorf("got %d parts, want 1", len(server.partHeaders))
+		}
+	})
+}
+
+func TestHeadObjectInfoUserMetadata(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amz-Meta-Owner", "team")
+		w.Header().Set("x-amz-meta-Build-Id", "42")
+		w.Header().Set("x-amz-version-id", "v1")
+	}))
+
+	info, err := client.HeadObjectInfo(context.Background(), "bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"owner": "team", "build-id": "42"}
+	if !maps.Equal(info.Metadata, want) {
+		t.Errorf("got metadata %v, want %v", info.Metadata, want)
+	}
+}
+
+func TestGetObjectPartRange(t *testing.T) {
+	tests := []struct {
+		byteRange Range
+		want      string
+	}{
+		{Range{Start: 0, End: 99}, "bytes=0-99"},
+		{Range{Start: 100, End: -1}, "bytes=100-"},
+		{Range{Start: -100, End: 5}, "bytes=-100"},
+	}
+
+	recorder := &requestRecorder{}
+	client := newTestClient(t, recorder)
+	for _, tt := range tests {
+		body, err := client.GetObjectPart(context.Background(), "bucket", "key", tt.byteRange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body.Close()
+		if got := recorder.header.Get("Range"); got != tt.want {
+			t.Errorf("%+v: got Range %q, want %q", tt.byteRange, got, tt.want)
+		}
+	}
+}
+
+func TestContextCancelAbortsRequestsAndBodyReads(t *testing.T) {
+	var requests int
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// send the first bytes, then stall until the client goes away
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.GetObject(canceled, "bucket", "key"); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+	if requests != 0 {
+		t.Errorf("got %d requests with a canceled context, want 0", requests)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body, err := client.GetObject(ctx, "bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(body, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := body.Read(buf)
+		done <- err
+	}()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got read error %v, want context.Canceled", err)
+	}
+}
+
+func TestPutObjectStreamServerSideEncryption(t *testing.T) {
+	recorder := &requestRecorder{}
+	client := newTestClient(t, recorder)
+
+	resp, err := client.PutObjectStream(context.Background(), "bucket", "key", strings.NewReader("data"), &PutObjectMetadata{
+		ContentLength:        4,
+		ServerSideEncryption: "aws:kms",
+		SSEKMSKeyId:          "arn:aws:kms:us-east-1:123456789012:key/example",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := recorder.header.Get("x-amz-server-side-encryption"); got != "aws:kms" {
+		t.Errorf("got x-amz-server-side-encryption %q, want aws:kms", got)
+	}
+	if got := recorder.header.Get("x-amz-server-side-encryption-aws-kms-key-id"); got != "arn:aws:kms:us-east-1:123456789012:key/example" {
+		t.Errorf("got x-amz-server-side-encryption-aws-kms-key-id %q", got)
+	}
+}
+
+func TestUploadPartSendsSSECustomerKey(t *testing.T) {
+	recorder := &requestRecorder{}
+	client := newTestClient(t, recorder)
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	_, err := client.UploadPart(context.Background(), "bucket", "key", strings.NewReader("data"), 4, 1, "upload-1", WithSSECustomerKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := http.Header{}
+	setSSECustomerHeaders(&http.Request{Header: want}, "x-amz-server-side-encryption-customer-", key)
+	for name := range want {
+		if got := recorder.header.Get(name); got != want.Get(name) {
+			t.Errorf("%s = %q, want %q", name, got, want.Get(name))
+		}
+	}
+}
+
+func TestSSECustomerKeyHeaders(t *testing.T) {
+	key := bytes.Repeat([]byte("B"), 32)
+	sseC := func(prefix string) map[string]string {
+		return map[string]string{
+			prefix + "algorithm": "AES256",
+			prefix + "key":       "QkJCQkJCQkJCQkJCQkJCQkJCQkJCQkJCQkJCQkJCQkI=",
+			prefix + "key-MD5":   "8NB6psqPvuXCjIqE3J2m5Q==",
+		}
+	}
+	recorder := &requestRecorder{response: `<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`}
+	client := newTestClient(t, recorder)
+	ctx := context.Background()
+
+	check := func(t *testing.T, want map[string]string) {
+		t.Helper()
+		for name, value := range want {
+			if got := recorder.header.Get(name); got != value {
+				t.Errorf("got %s %q, want %q", name, got, value)
+			}
+		}
+	}
+
+	t.Run("GetObject", func(t *testing.T) {
+		body, err := client.GetObject(ctx, "bucket", "key", WithSSECustomerKey(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body.Close()
+		check(t, sseC("x-amz-server-side-encryption-customer-"))
+	})
+
+	t.Run("PutObject", func(t *testing.T) {
+		if err := client.PutObject(ctx, "bucket", "key", []byte("data"), WithSSECustomerKey(key)); err != nil {
+			t.Fatal(err)
+		}
+		check(t, sseC("x-amz-server-side-encryption-customer-"))
+	})
+
+	t.Run("CopyObject", func(t *testing.T) {
+		if _, err := client.CopyObject(ctx, "src", "a", "dst", "b", &CopyOptions{SourceSSECustomerKey: key, SSECustomerKey: key}); err != nil {
+			t.Fatal(err)
+		}
+		check(t, sseC("x-amz-copy-source-server-side-encryption-customer-"))
+		check(t, sseC("x-amz-server-side-encryption-customer-"))
+	})
+}
+
+func TestPutObjectWithInfoResult(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"5d41402abc4b2a76b9719d911017c592"`)
+		w.Header().Set("x-amz-version-id", "3HL4kqtJlcpXroDTDmJ+rmSpXd3dIbrHY")
+		w.Header().Set("x-amz-server-side-encryption", "AES256")
+		w.Header().Set("x-amz-checksum-crc32", "NhCmhg==")
+	}))
+
+	result, err := client.PutObjectWithInfo(context.Background(), "bucket", "key", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PutObjectResult{
+		ETag:                 "5d41402abc4b2a76b9719d911017c592",
+		VersionId:            "3HL4kqtJlcpXroDTDmJ+rmSpXd3dIbrHY",
+		ServerSideEncryption: "AES256",
+		Checksum:             Checksum{ChecksumCRC32: "NhCmhg=="},
+	}
+	if *result != want {
+		t.Errorf("got %+v, want %+v", *result, want)
+	}
+}
+
+func TestPutObjectStreamVerifyMD5(t *testing.T) {
+	var requests int
+	var header http.Header
+	var body string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requests++
+		header, body = r.Header.Clone(), string(data)
+	}))
+
+	resp, err := client.PutObjectStream(context.Background(), "bucket", "key", strings.NewReader("hello"), &PutObjectMetadata{ContentLength: 5, VerifyMD5: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, _ := buildContentHash([]byte("hello")); header.Get("Content-MD5") != want {
+		t.Errorf("got Content-MD5 %q, want %q", header.Get("Content-MD5"), want)
+	}
+	if body != "hello" {
+		t.Errorf("got body %q after hashing, want %q", body, "hello")
+	}
+
+	// a body that can not be read twice is rejected before sending
+	_, err = client.PutObjectStream(context.Background(), "bucket", "key", io.MultiReader(strings.NewReader("hello")), &PutObjectMetadata{ContentLength: 5, VerifyMD5: true})
+	if err == nil {
+		t.Error("got no error for a body without io.ReadSeeker")
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
+
+func TestConditionalReadsByModificationTime(t *testing.T) {
+	lastModified := exampleTime
+	var header http.Header
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if since, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && lastModified.After(since) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+			return
+		}
+		fmt.Fprint(w, "data")
+	}))
+	ctx := context.Background()
+
+	_, err := client.GetObject(ctx, "bucket", "key", WithIfModifiedSince(lastModified))
+	if !errors.Is(err, ErrNotModified) {
+		t.Errorf("got error %v, want ErrNotModified", err)
+	}
+	if got := header.Get("If-Modified-Since"); got != "Fri, 24 May 2013 00:00:00 GMT" {
+		t.Errorf("got If-Modified-Since %q", got)
+	}
+
+	_, err = client.GetObject(ctx, "bucket", "key", WithIfUnmodifiedSince(lastModified.Add(-time.Hour)))
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("got error %v, want ErrPreconditionFailed", err)
+	}
+
+	body, err := client.GetObject(ctx, "bucket", "key", WithIfModifiedSince(lastModified.Add(-time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+}
+
+func TestUploadUserMetadata(t *testing.T) {
+	recorder := &requestRecorder{}
+	client := newTestClient(t, recorder)
+	ctx := context.Background()
+	want := map[string]string{"x-amz-meta-owner": "team", "x-amz-meta-build-id": "42"}
+	check := func(t *testing.T) {
+		t.Helper()
+		for name, value := range want {
+			if got := recorder.header.Get(name); got != value {
+				t.Errorf("got %s %q, want %q", name, got, value)
+			}
+		}
+		if authorization := recorder.header.Get("Authorization"); !strings.Contains(authorization, "x-amz-meta-build-id;x-amz-meta-owner") {
+			t.Errorf("metadata is not signed: %s", authorization)
+		}
+	}
+
+	t.Run("PutObject", func(t *testing.T) {
+		if err := client.PutObject(ctx, "bucket", "key", []byte("data"), WithUserMetadata(map[string]string{"Owner": "team", "Build-Id": "42"})); err != nil {
+			t.Fatal(err)
+		}
+		check(t)
+	})
+
+	t.Run("PutObjectStream", func(t *testing.T) {
+		resp, err := client.PutObjectStream(ctx, "bucket", "key", strings.NewReader("data"), &PutObjectMetadata{
+			ContentLength: 4,
+			UserMetadata:  map[string]string{"owner": "team", "build-id": "42"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		check(t)
+	})
+}
+
+func TestUploadStorageClass(t *testing.T) {
+	recorder := &requestRecorder{}
+	client := newTestClient(t, recorder)
+	ctx := context.Background()
+
+	if err := client.PutObject(ctx, "bucket", "key", []byte("data"), WithStorageClass("INTELLIGENT_TIERING")); err != nil {
+		t.Fatal(err)
+	}
+	if got := recorder.header.Get("x-amz-storage-class"); got != "INTELLIGENT_TIERING" {
+		t.Errorf("PutObject: got x-amz-storage-class %q, want INTELLIGENT_TIERING", got)
+	}
+
+	resp, err := client.PutObjectStream(ctx, "bucket", "key", strings.NewReader("data"), &PutObjectMetadata{ContentLength: 4, StorageClass: "GLACIER"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := recorder.header.Get("x-amz-storage-class"); got != "GLACIER" {
+		t.Errorf("PutObjectStream: got x-amz-storage-class %q, want GLACIER", got)
+	}
+
+	// the storage class only applies to uploads
+	body, err := client.GetObject(ctx, "bucket", "key", WithStorageClass("GLACIER"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if got := recorder.header.Get("x-amz-storage-class"); got != "" {
+		t.Errorf("GetObject: got x-amz-storage-class %q", got)
+	}
+}
+
+func TestStreamingUploadErrors(t *testing.T) {
+	var contentLengths []int64
+	var transferEncodings []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		contentLengths = append(contentLengths, r.ContentLength)
+		transferEncodings = append(transferEncodings, strings.Join(r.TransferEncoding, ","))
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+	}))
+	ctx := context.Background()
+
+	_, err := client.PutObjectStream(ctx, "bucket", "key", strings.NewReader("data"), &PutObjectMetadata{ContentLength: 4})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("PutObjectStream: got error %v, want ErrAccessDenied", err)
+	}
+	_, err = client.UploadPart(ctx, "bucket", "key", strings.NewReader("data"), 4, 1, "upload-1")
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("UploadPart: got error %v, want ErrAccessDenied", err)
+	}
+
+	// streamed bodies are sent with their length instead of chunked
+	for i := range contentLengths {
+		if contentLengths[i] != 4 || transferEncodings[i] != "" {
+			t.Errorf("request %d: got Content-Length %d and Transfer-Encoding %q", i+1, contentLengths[i], transferEncodings[i])
+		}
+	}
+}
+
+func TestUploadPartReturnsUnquotedETag(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("ETag", `"part-etag"`)
+	}))
+	ctx := context.Background()
+
+	part, err := client.UploadPart(ctx, "bucket", "key", strings.NewReader("data"), 4, 1, "upload-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.ETag != "part-etag" || part.PartNumber != 1 {
+		t.Errorf("UploadPart: got %+v", *part)
+	}
+
+	part, err = client.UploadPartWithChecksum(ctx, "bucket", "key", []byte("data"), 2, "upload-1", "CRC32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.ETag != "part-etag" || part.PartNumber != 2 {
+		t.Errorf("UploadPartWithChecksum: got %+v", *part)
+	}
+}
+
+func TestETagsAreUnquotedAndQuotedOnTheWire(t *testing.T) {
+	const etag = "9bb58f26192e4ba00f01e2e7b136bbd8"
+	var ifMatch, completion string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("ETag", `"`+etag+`"`)
+		switch {
+		case r.URL.Query().Has("uploadId") && r.Method == http.MethodPost:
+			completion = string(body)
+			fmt.Fprintf(w, `<CompleteMultipartUploadResult><ETag>&quot;%s-1&quot;</ETag></CompleteMultipartUploadResult>`, etag)
+		case r.Header.Get("If-Match") != "":
+			ifMatch = r.Header.Get("If-Match")
+			w.WriteHeader(http.StatusPreconditionFailed)
+		}
+	}))
+	ctx := context.Background()
+
+	head, err := client.HeadObjectInfo(ctx, "bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.ETag != etag {
+		t.Errorf("HeadObjectInfo: got ETag %s, want %s", head.ETag, etag)
+	}
+
+	put, err := client.PutObjectWithInfo(ctx, "bucket", "key", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if put.ETag != etag {
+		t.Errorf("PutObjectWithInfo: got ETag %s, want %s", put.ETag, etag)
+	}
+
+	completed, err := client.CompleteMultipartUpload(ctx, "bucket", "key", "upload-1", []CompletedPart{{PartNumber: 1, ETag: etag}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed.ETag != etag+"-1" {
+		t.Errorf("CompleteMultipartUpload: got ETag %s, want %s-1", completed.ETag, etag)
+	}
+	if want := "<ETag>&#34;" + etag + "&#34;</ETag>"; !strings.Contains(completion, want) {
+		t.Errorf("completion does not contain the quoted part ETag %s: %s", want, completion)
+	}
+
+	_, err = client.HeadObject(ctx, "bucket", "key", WithIfMatch(etag))
+	var preconditionFailed *PreconditionFailedError
+	if !errors.As(err, &preconditionFailed) {
+		t.Fatalf("got error %v, want a PreconditionFailedError", err)
+	}
+	if ifMatch != `"`+etag+`"` {
+		t.Errorf("got If-Match %s, want the quoted ETag", ifMatch)
+	}
+	if preconditionFailed.CurrentETag != etag {
+		t.Errorf("got CurrentETag %s, want %s", preconditionFailed.CurrentETag, etag)
+	}
+}
+
+// readSizes records the size of every read from a streamed body.
+type readSizes struct {
+	src   io.Reader
+	sizes []int
+}
+
+func (r *readSizes) Read(p []byte) (int, error) {
+	r.sizes = append(r.sizes, len(p))
+	return r.src.Read(p)
+}
+
+func TestStreamedUploadChunkSize(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		opts []ObjectOption
+		want int
+	}{
+		{name: "default", want: defaultChunkSize},
+		{name: "option", opts: []ObjectOption{WithChunkSize(1024)}, want: 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &readSizes{src: bytes.NewReader(make([]byte, 256*1024))}
+			resp, err := client.PutObjectStream(ctx, "bucket", "key", body, &PutObjectMetadata{ContentLength: 256 * 1024}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			for _, size := range body.sizes {
+				if size > tt.want {
+					t.Fatalf("got a read of %d bytes, want at most %d", size, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPutObjectStreamChunkSize(b *testing.B) {
+	client := newTestClient(b, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	data := make([]byte, 8*1024*1024)
+
+	for _, chunkSize := range []int{4 * 1024, 64 * 1024, 1024 * 1024} {
+		b.Run(fmt.Sprintf("%dKiB", chunkSize/1024), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for range b.N {
+				resp, err := client.PutObjectStream(context.Background(), "bucket", "key", bytes.NewReader(data), &PutObjectMetadata{ContentLength: int64(len(data))}, WithChunkSize(chunkSize))
+				if err != nil {
+					b.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+		})
+	}
+}
+
+func TestDeleteObjectsDecodesResult(t *testing.T) {
+	recorder := &requestRecorder{response: `<DeleteResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">` +
+		`<Deleted><Key>a</Key><DeleteMarker>true</DeleteMarker><DeleteMarkerVersionId>marker-1</DeleteMarkerVersionId></Deleted>` +
+		`<Deleted><Key>b</Key><VersionId>v2</VersionId></Deleted>` +
+		`<Error><Key>c</Key><VersionId>v3</VersionId><Code>AccessDenied</Code><Message>Access Denied</Message></Error>` +
+		`</DeleteResult>`}
+	client := newTestClient(t, recorder)
+
+	result, err := client.DeleteObjects(context.Background(), "bucket", Delete{Objects: []ObjectIdentifier{{Key: "a"}, {Key: "b", VersionId: "v2"}, {Key: "c", VersionId: "v3"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantDeleted := []DeletedObject{
+		{Key: "a", DeleteMarker: true, DeleteMarkerVersionId: "marker-1"},
+		{Key: "b", VersionId: "v2"},
+	}
+	if !slices.Equal(result.Deleted, wantDeleted) {
+		t.Errorf("got deleted %+v, want %+v", result.Deleted, wantDeleted)
+	}
+	wantErrors := []Error{{Key: "c", VersionId: "v3", Code: "AccessDenied", Message: "Access Denied"}}
+	if !slices.Equal(result.Errors, wantErrors) {
+		t.Errorf("got errors %+v, want %+v", result.Errors, wantErrors)
+	}
+}
+
+func TestBucketCorsRoundTrip(t *testing.T) {
+	var stored string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.Query().Has("cors") {
+			http.Error(w, "not a cors request", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if want, _ := buildContentHash(body); r.Header.Get("Content-MD5") != want {
+				t.Errorf("got Content-MD5 %q, want %q", r.Header.Get("Content-MD5"), want)
+			}
+			stored = string(body)
+		case http.MethodGet:
+			fmt.Fprint(w, stored)
+		case http.MethodDelete:
+			stored = ""
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	ctx := context.Background()
+
+	rule := CORSRule{
+		ID:             "web",
+		AllowedOrigins: []string{"https://example.com"},
+		AllowedMethods: []string{"GET", "PUT"},
+		AllowedHeaders: []string{"*"},
+		ExposeHeaders:  []string{"ETag"},
+		MaxAgeSeconds:  3000,
+	}
+	if err := client.PutBucketCors(ctx, "bucket", CORSConfiguration{CORSRules: []CORSRule{rule}}); err != nil {
+		t.Fatal(err)
+	}
+	config, err := client.GetBucketCors(ctx, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.CORSRules) != 1 {
+		t.Fatalf("got %d rules, want 1", len(config.CORSRules))
+	}
+	got := config.CORSRules[0]
+	if got.ID != rule.ID || got.MaxAgeSeconds != rule.MaxAgeSeconds ||
+		!slices.Equal(got.AllowedOrigins, rule.AllowedOrigins) || !slices.Equal(got.AllowedMethods, rule.AllowedMethods) ||
+		!slices.Equal(got.AllowedHeaders, rule.AllowedHeaders) || !slices.Equal(got.ExposeHeaders, rule.ExposeHeaders) {
+		t.Errorf("got rule %+v, want %+v", got, rule)
+	}
+
+	if err := client.DeleteBucketCors(ctx, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if stored != "" {
+		t.Error("configuration not deleted")
+	}
+}
+
+func TestPutBucketEncryption(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ServerSideEncryptionConfiguration
+		want   string
+	}{
+		{
+			name: "AES256",
+			config: ServerSideEncryptionConfiguration{Rules: []ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &ServerSideEncryptionByDefault{SSEAlgorithm: "AES256"},
+			}}},
+			want: `<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>AES256</SSEAlgorithm></ApplyServerSideEncryptionByDefault><BucketKeyEnabled>false</BucketKeyEnabled></Rule></ServerSideEncryptionConfiguration>`,
+		},
+		{
+			name: "aws:kms",
+			config: ServerSideEncryptionConfiguration{Rules: []ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &ServerSideEncryptionByDefault{SSEAlgorithm: "aws:kms", KMSMasterKeyID: "arn:aws:kms:us-east-1:123456789012:key/example"},
+				BucketKeyEnabled:                   true,
+			}}},
+			want: `<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>aws:kms</SSEAlgorithm><KMSMasterKeyID>arn:aws:kms:us-east-1:123456789012:key/example</KMSMasterKeyID></ApplyServerSideEncryptionByDefault><BucketKeyEnabled>true</BucketKeyEnabled></Rule></ServerSideEncryptionConfiguration>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &requestRecorder{}
+			client := newTestClient(t, recorder)
+
+			if err := client.PutBucketEncryption(context.Background(), "bucket", tt.config); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.method != http.MethodPut || !recorder.query.Has("encryption") {
+				t.Errorf("got %s with query %v", recorder.method, recorder.query)
+			}
+			if recorder.body != tt.want {
+				t.Errorf("got body\n%s\nwant\n%s", recorder.body, tt.want)
+			}
+			if want, _ := buildContentHash([]byte(tt.want)); recorder.header.Get("Content-MD5") != want {
+				t.Errorf("got Content-MD5 %q, want %q", recorder.header.Get("Content-MD5"), want)
+			}
+		})
+	}
+}
+
+func TestPutBucketReplication(t *testing.T) {
+	recorder := &requestRecorder{}
+	client := newTestClient(t, recorder)
+
+	config := ReplicationConfiguration{
+		Role: "arn:aws:iam::123456789012:role/replication",
+		Rules: []ReplicationRule{{
+			ID:       "logs",
+			Priority: 1,
+			Status:   "Enabled",
+			Filter:   &ReplicationRuleFilter{Prefix: "logs/"},
+			Destination: ReplicationDestination{
+				Bucket:       "arn:aws:s3:::replica",
+				StorageClass: "STANDARD_IA",
+			},
+			DeleteMarkerReplication: &DeleteMarkerReplication{Status: "Disabled"},
+		}},
+	}
+	if err := client.PutBucketReplication(context.Background(), "bucket", config); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<ReplicationConfiguration><Role>arn:aws:iam::123456789012:role/replication</Role>` +
+		`<Rule><ID>logs</ID><Priority>1</Priority><Status>Enabled</Status><Filter><Prefix>logs/</Prefix></Filter>` +
+		`<Destination><Bucket>arn:aws:s3:::replica</Bucket><StorageClass>STANDARD_IA</StorageClass></Destination>` +
+		`<DeleteMarkerReplication><Status>Disabled</Status></DeleteMarkerReplication></Rule></ReplicationConfiguration>`
+	if recorder.method != http.MethodPut || !recorder.query.Has("replication") {
+		t.Errorf("got %s with query %v", recorder.method, recorder.query)
+	}
+	if recorder.body != want {
+		t.Errorf("got body\n%s\nwant\n%s", recorder.body, want)
+	}
+	if hash, _ := buildContentHash([]byte(want)); recorder.header.Get("Content-MD5") != hash {
+		t.Errorf("got Content-MD5 %q, want %q", recorder.header.Get("Content-MD5"), hash)
+	}
+}
+
+func TestListObjectsV2InputQuery(t *testing.T) {
+	input := ListObjectsV2Input{
+		Prefix:     "logs/",
+		MaxKeys:    250,
+		FetchOwner: true,
+	}
+	want := map[string]string{
+		"prefix":      "logs/",
+		"max-keys":    "250",
+		"fetch-owner": "true",
+	}
+	if got := input.query(); !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// zero values are left to the service defaults
+	if got := (ListObjectsV2Input{}).query(); len(got) != 0 {
+		t.Errorf("got %v for an empty input", got)
+	}
+}
+
+func TestParseHeadObjectResult(t *testing.T) {
+	resp := &http.Response{ContentLength: 42, Header: http.Header{}}
+	resp.Header.Set("ETag", `"etag"`)
+	resp.Header.Set("Last-Modified", "Fri, 24 May 2013 00:00:00 GMT")
+	resp.Header.Set("x-amz-storage-class", "GLACIER_IR")
+	resp.Header.Set("x-amz-tagging-count", "3")
+	resp.Header.Set("x-amz-meta-owner", "team")
+
+	got := parseHeadObjectResult(resp)
+	if got.ContentLength != 42 || got.ETag != "etag" || !got.LastModified.Equal(exampleTime) {
+		t.Errorf("got %+v", got)
+	}
+	if got.StorageClass != "GLACIER_IR" {
+		t.Errorf("got storage class %q, want GLACIER_IR", got.StorageClass)
+	}
+	if got.TaggingCount != 3 {
+		t.Errorf("got tagging count %d, want 3", got.TaggingCount)
+	}
+	if got.Metadata["owner"] != "team" {
+		t.Errorf("got metadata %v", got.Metadata)
+	}
+
+	// objects in the STANDARD class and without tags omit the headers
+	if got := parseHeadObjectResult(&http.Response{Header: http.Header{}}); got.StorageClass != "" || got.TaggingCount != 0 {
+		t.Errorf("got storage class %q and tagging count %d without headers", got.StorageClass, got.TaggingCount)
+	}
+}
+
+func TestObjectKeyPathMatchesCanonicalURI(t *testing.T) {
+	keys := map[string]string{
+		"my file (1).txt": "/bucket/my%20file%20%281%29.txt",
+		"emoji 😀.png":     "/bucket/emoji%20%F0%9F%98%80.png",
+		"a+b=c":           "/bucket/a%2Bb%3Dc",
+		"dir/a b/c":       "/bucket/dir/a%20b/c",
+	}
+
+	var sent string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = r.RequestURI
+	}))
+
+	for key, want := range keys {
+		t.Run(key, func(t *testing.T) {
+			req, err := client.newRequest(context.Background(), http.MethodGet, "bucket", key, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the canonical URI is the second line of the canonical request
+			canonicalURI := strings.Split(getCanonicalRequest(req, emptyPayloadHash, getSignedHeaders(req)), "\n")[1]
+			if canonicalURI != want {
+				t.Errorf("got canonical URI %s, want %s", canonicalURI, want)
+			}
+
+			resp, err := client.do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if sent != canonicalURI {
+				t.Errorf("sent path %s, signed %s", sent, canonicalURI)
+			}
+		})
+	}
+}
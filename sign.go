@@ -6,7 +6,11 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,57 +18,213 @@ const (
 	userAgent  = "spin-s3"
 	timeFormat = "20060102T150405Z"
 	dateFormat = "20060102"
+	// service name of the credential scope unless configured otherwise
+	defaultSigningService = "s3"
 )
-const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
-
-func getAuthorizationHeader(req *http.Request, payloadHash, region, accessKey, secretKey string, now time.Time) string {
-	canonicalRequest := getCanonicalRequest(req, payloadHash, now)
-	stringToSign := getStringToSign(canonicalRequest, region, now)
-	signature := getSignature(stringToSign, region, secretKey, now)
-	credential := strings.Join([]string{
-		accessKey, now.Format(dateFormat), region, "s3", "aws4_request",
-	}, "/")
+
+func getAuthorizationHeader(req *http.Request, payloadHash, region, service, accessKey, secretKey string, now time.Time) string {
+	signedHeaders := getSignedHeaders(req)
+	canonicalRequest := getCanonicalRequest(req, payloadHash, signedHeaders)
+	stringToSign := getStringToSign(canonicalRequest, region, service, now)
+	signature := getSignature(stringToSign, region, service, secretKey, now)
 	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s, SignedHeaders=%s, Signature=%s",
-		credential, signedHeaders, signature)
+		getCredential(accessKey, region, service, now), strings.Join(signedHeaders, ";"), signature)
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+// presignRequest adds the authentication parameters and the signature to the query of req.
+// Only the host header is signed and the payload is left unsigned.
+func presignRequest(req *http.Request, region, service, accessKey, secretKey, sessionToken string, expiry time.Duration, now time.Time) {
+	signedHeaders := []string{"host"}
+
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", getCredential(accessKey, region, service, now))
+	query.Set("X-Amz-Date", now.Format(timeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expiry/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", strings.Join(signedHeaders, ";"))
+	if sessionToken != "" {
+		query.Set("X-Amz-Security-Token", sessionToken)
+	}
+	req.URL.RawQuery = canonicalQuery(query)
+
+	canonicalRequest := getCanonicalRequest(req, "UNSIGNED-PAYLOAD", signedHeaders)
+	stringToSign := getStringToSign(canonicalRequest, region, service, now)
+	signature := getSignature(stringToSign, region, service, secretKey, now)
+
+	req.URL.RawQuery += "&X-Amz-Signature=" + signature
+}
+
+// getCredential returns the access key together with the credential scope.
+func getCredential(accessKey, region, service string, now time.Time) string {
+	return strings.Join([]string{
+		accessKey, now.Format(dateFormat), region, service, "aws4_request",
+	}, "/")
+}
+
+// unsignedHeaders are left out of the signature because they are set by the
+// transport or commonly rewritten by proxies.
+var unsignedHeaders = map[string]bool{
+	"authorization":     true,
+	"content-length":    true,
+	"user-agent":        true,
+	"expect":            true,
+	"connection":        true,
+	"transfer-encoding": true,
+	"x-amzn-trace-id":   true,
+}
+
+// getSignedHeaders returns the sorted, lowercase names of the headers covered
+// by the signature: the host and every header of the request except those in
+// unsignedHeaders, so Content-Type, Content-MD5, x-amz-meta-* and all other
+// headers sent can not be altered.
+func getSignedHeaders(req *http.Request) []string {
+	headers := []string{"host"}
+	for name := range req.Header {
+		name = strings.ToLower(name)
+		if name != "host" && !unsignedHeaders[name] {
+			headers = append(headers, name)
+		}
+	}
+	sort.Strings(headers)
+	return headers
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html#request-string
-func getStringToSign(canonicalRequest, region string, now time.Time) string {
+func getStringToSign(canonicalRequest, region, service string, now time.Time) string {
 	// Create the hash of the canonical request
 	canonicalRequestHash := sha256.New()
 	canonicalRequestHash.Write([]byte(canonicalRequest))
 	canonicalRequestHashString := hex.EncodeToString(canonicalRequestHash.Sum(nil))
 
 	// Create the string to sign
-	return fmt.Sprintf("AWS4-HMAC-SHA256\n%s\n%s/%s/s3/aws4_request\n%s",
-		now.Format(timeFormat), now.Format(dateFormat), region, canonicalRequestHashString)
+	return fmt.Sprintf("AWS4-HMAC-SHA256\n%s\n%s/%s/%s/aws4_request\n%s",
+		now.Format(timeFormat), now.Format(dateFormat), region, service, canonicalRequestHashString)
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html#signing-key
-func getSignature(stringToSign, region, secretKey string, now time.Time) string {
-	dateKey := hmacSHA256([]byte("AWS4"+secretKey), []byte(now.Format(dateFormat)))
-	regionKey := hmacSHA256(dateKey, []byte(region))
-	serviceKey := hmacSHA256(regionKey, []byte("s3"))
-	signingKey := hmacSHA256(serviceKey, []byte("aws4_request"))
-
+func getSignature(stringToSign, region, service, secretKey string, now time.Time) string {
+	signingKey := signingKeys.get(secretKey, now.Format(dateFormat), region, service)
 	return hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
 }
 
+// signingKeys caches the derived signing keys of the latest and the previous day.
+var signingKeys = &signingKeyCache{keys: make(map[signingKeyScope][]byte)}
+
+type signingKeyScope struct {
+	secretKey, date, region, service string
+}
+
+// signingKeyCache holds signing keys by credential scope. A signing key is
+// only valid for a single day, so keys are dropped once a date two days
+// newer is requested. Keys of the previous day are kept for clients that
+// still sign with it, e.g. with the server time around midnight.
+type signingKeyCache struct {
+	mu     sync.Mutex
+	latest string
+	keys   map[signingKeyScope][]byte
+}
+
+// get returns the signing key for the scope and derives it if not cached.
+func (c *signingKeyCache) get(secretKey, date, region, service string) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// dates are formatted as YYYYMMDD, so they sort chronologically
+	if date > c.latest {
+		for scope := range c.keys {
+			if scope.date < c.latest {
+				delete(c.keys, scope)
+			}
+		}
+		c.latest = date
+	}
+	scope := signingKeyScope{secretKey: secretKey, date: date, region: region, service: service}
+	if key, ok := c.keys[scope]; ok {
+		return key
+	}
+
+	key := deriveSigningKey(secretKey, date, region, service)
+	c.keys[scope] = key
+	return key
+}
+
+// deriveSigningKey derives the signing key of a credential scope from the secret key.
+func deriveSigningKey(secretKey, date, region, service string) []byte {
+	dateKey := hmacSHA256([]byte("AWS4"+secretKey), []byte(date))
+	regionKey := hmacSHA256(dateKey, []byte(region))
+	serviceKey := hmacSHA256(regionKey, []byte(service))
+	return hmacSHA256(serviceKey, []byte("aws4_request"))
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html#canonical-request
-func getCanonicalRequest(req *http.Request, payloadHash string, now time.Time) string {
-	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n",
-		req.Host, payloadHash, now.Format(timeFormat))
+func getCanonicalRequest(req *http.Request, payloadHash string, signedHeaders []string) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		values := req.Header.Values(name)
+		if name == "host" {
+			values = []string{req.Host}
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			// trim and collapse sequential spaces
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.Join(trimmed, ",") + "\n")
+	}
 
 	return strings.Join([]string{
 		req.Method,
 		req.URL.EscapedPath(),
 		req.URL.RawQuery,
-		canonicalHeaders,
-		signedHeaders,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
 		payloadHash,
 	}, "\n")
 }
 
+// uriEncode percent-encodes s as required for the canonical request: all
+// bytes except the unreserved characters A-Z, a-z, 0-9, '-', '.', '_' and '~'
+// are encoded, '/' only if encodeSlash is set.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html#create-canonical-request
+func uriEncode(s string, encodeSlash bool) string {
+	const hex = "0123456789ABCDEF"
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			encoded.WriteByte('%')
+			encoded.WriteByte(hex[c>>4])
+			encoded.WriteByte(hex[c&15])
+		}
+	}
+	return encoded.String()
+}
+
+// canonicalQuery encodes the query parameters sorted by name with uriEncode.
+// Unlike url.Values.Encode, spaces are encoded as %20 instead of '+'.
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var query []string
+	for _, k := range keys {
+		for _, v := range values[k] {
+			query = append(query, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(query, "&")
+}
+
 func hmacSHA256(key, data []byte) []byte {
 	hash := hmac.New(sha256.New, key)
 	hash.Write(data)
@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// errNoAttributesDocument marks a GetObjectAttributes response that can not be
+// decoded, as sent by services ignoring the attributes query.
+var errNoAttributesDocument = errors.New("no attributes document")
+
+// ObjectSummary describes an object as returned by Stat.
+type ObjectSummary struct {
+	Size int64
+	// ETag without surrounding quotes
+	ETag         string
+	StorageClass string
+	LastModified time.Time
+	VersionId    string
+	// Stored checksums of the object, only filled in by GetObjectAttributes
+	Checksum Checksum
+}
+
+// Stat returns the size, ETag, storage class and checksums of an object.
+// It uses a single GetObjectAttributes request and falls back to HeadObject
+// for services that do not implement it, answering with 501 Not Implemented
+// or with a body that is no attributes document, e.g. the object itself. The
+// missing support is remembered, so later calls go to HeadObject directly.
+func (c *Client) Stat(ctx context.Context, bucketName, objectName string) (*ObjectSummary, error) {
+	if !c.attributesUnsupported.Load() {
+		attributes, err := c.GetObjectAttributes(ctx, bucketName, objectName, nil)
+		if err == nil {
+			return &ObjectSummary{
+				Size:         attributes.ObjectSize,
+				ETag:         trimETag(attributes.ETag),
+				StorageClass: storageClassOrDefault(attributes.StorageClass),
+				LastModified: attributes.LastModified,
+				VersionId:    attributes.VersionId,
+				Checksum:     attributes.Checksum,
+			}, nil
+		}
+		if !isNotImplemented(err) && !errors.Is(err, errNoAttributesDocument) {
+			return nil, err
+		}
+		c.attributesUnsupported.Store(true)
+	}
+
+	resp, err := c.HeadObject(ctx, bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	head := parseHeadObjectResult(resp)
+	return &ObjectSummary{
+		Size:         head.ContentLength,
+		ETag:         head.ETag,
+		StorageClass: storageClassOrDefault(head.StorageClass),
+		LastModified: head.LastModified,
+		VersionId:    head.VersionId,
+	}, nil
+}
+
+// storageClassOrDefault returns STANDARD for an empty storage class, which
+// S3 omits for objects in the standard class.
+func storageClassOrDefault(storageClass string) string {
+	if storageClass == "" {
+		return "STANDARD"
+	}
+	return storageClass
+}
+
+// isNotImplemented reports whether err is a 501 Not Implemented response.
+func isNotImplemented(err error) bool {
+	var errorResponse ErrorResponse
+	return errors.As(err, &errorResponse) &&
+		(errorResponse.StatusCode == http.StatusNotImplemented || errorResponse.Code == "NotImplemented")
+}
@@ -0,0 +1,101 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+// attributesServer answers GetObjectAttributes with attributes, or with the
+// status and body of a service not supporting it, and HEAD requests with the
+// object headers. It counts the attributes requests.
+type attributesServer struct {
+	status     int
+	attributes string
+	requests   atomic.Int32
+}
+
+func (s *attributesServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("ETag", `"abc"`)
+	w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Length", "11")
+		w.Header().Set("x-amz-storage-class", "STANDARD_IA")
+		return
+	}
+	if !r.URL.Query().Has("attributes") {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	s.requests.Add(1)
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+	}
+	fmt.Fprint(w, s.attributes)
+}
+
+func TestStatUsesObjectAttributes(t *testing.T) {
+	server := &attributesServer{attributes: `<?xml version="1.0" encoding="UTF-8"?>
+<GetObjectAttributesResponse xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <ETag>"abc"</ETag>
+  <Checksum><ChecksumCRC32>i9aeUg==</ChecksumCRC32></Checksum>
+  <ObjectSize>11</ObjectSize>
+</GetObjectAttributesResponse>`}
+	client := newTestClient(t, server)
+
+	summary, err := client.Stat(context.Background(), "bucket", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Size != 11 || summary.ETag != "abc" || summary.StorageClass != "STANDARD" ||
+		summary.Checksum.ChecksumCRC32 != "i9aeUg==" || summary.LastModified.IsZero() {
+		t.Errorf("got summary %+v", summary)
+	}
+}
+
+func TestStatFallsBackToHeadObject(t *testing.T) {
+	tests := map[string]*attributesServer{
+		"not implemented": {
+			status:     http.StatusNotImplemented,
+			attributes: `<Error><Code>NotImplemented</Code><Message>A header you provided implies functionality that is not implemented</Message></Error>`,
+		},
+		"object body": {attributes: "hello world"},
+		"empty body":  {},
+	}
+	for name, server := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := newTestClient(t, server)
+
+			for range 2 {
+				summary, err := client.Stat(context.Background(), "bucket", "key")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if summary.Size != 11 || summary.ETag != "abc" || summary.StorageClass != "STANDARD_IA" {
+					t.Errorf("got summary %+v", summary)
+				}
+			}
+			// the missing support is cached after the first call
+			if got := server.requests.Load(); got != 1 {
+				t.Errorf("got %d attributes requests, want 1", got)
+			}
+		})
+	}
+}
+
+func TestStatReturnsOtherErrors(t *testing.T) {
+	server := &attributesServer{
+		status:     http.StatusForbidden,
+		attributes: `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`,
+	}
+	client := newTestClient(t, server)
+
+	if _, err := client.Stat(context.Background(), "bucket", "key"); err == nil {
+		t.Fatal("got no error for a denied request")
+	}
+	if client.attributesUnsupported.Load() {
+		t.Error("denied request cached as missing support")
+	}
+}
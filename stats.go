@@ -0,0 +1,84 @@
+package s3
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// TransferStats summarizes a transfer of Upload, UploadLarge,
+// DownloadToWriter or Downloader.DownloadFile, e.g. to tune the part size and
+// concurrency. Uploads return it in PutObjectResult.Stats, all of them also
+// if the transfer failed.
+type TransferStats struct {
+	// Bytes of the object transferred, including completed parts of a failed transfer
+	Bytes int64
+	// Size of the object, above Bytes if a resumed download skipped parts; for a
+	// failed upload of unknown size the bytes read so far
+	Size int64
+	// Time from the start of the transfer until it returned
+	Elapsed time.Duration
+	// Number of parts or ranges transferred completely, an empty upload is one part
+	Parts int
+	// Number of requests that were sent again after a failure
+	Retries int
+}
+
+// MBPerSecond returns the effective throughput in megabytes (10^6 bytes) per second.
+func (s TransferStats) MBPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / 1e6 / s.Elapsed.Seconds()
+}
+
+// transferCounterKey is the context key of the counter of a running transfer.
+type transferCounterKey struct{}
+
+// transferCounter counts the progress of a running transfer.
+type transferCounter struct {
+	start   time.Time
+	bytes   atomic.Int64
+	parts   atomic.Int64
+	retries atomic.Int64
+}
+
+// startTransfer starts counting a transfer. The requests sent with the
+// returned context count their retries into the returned counter. Transfers
+// nested into another one, e.g. UploadLarge called by Upload, are counted by
+// the outer transfer.
+func startTransfer(ctx context.Context) (context.Context, *transferCounter) {
+	if counter, ok := ctx.Value(transferCounterKey{}).(*transferCounter); ok {
+		return ctx, counter
+	}
+
+	counter := &transferCounter{start: time.Now()}
+	return context.WithValue(ctx, transferCounterKey{}, counter), counter
+}
+
+// stats returns the stats of the transfer of an object of the given size so far.
+func (c *transferCounter) stats(size int64) TransferStats {
+	return TransferStats{
+		Bytes:   c.bytes.Load(),
+		Size:    size,
+		Elapsed: time.Since(c.start),
+		Parts:   int(c.parts.Load()),
+		Retries: int(c.retries.Load()),
+	}
+}
+
+// countTransferred adds transferred bytes and completed parts to the
+// transfer of ctx, if it is counted.
+func countTransferred(ctx context.Context, bytes int64, parts int) {
+	if counter, ok := ctx.Value(transferCounterKey{}).(*transferCounter); ok {
+		counter.bytes.Add(bytes)
+		counter.parts.Add(int64(parts))
+	}
+}
+
+// countRetry adds a retried request to the transfer of ctx, if it is counted.
+func countRetry(ctx context.Context) {
+	if counter, ok := ctx.Value(transferCounterKey{}).(*transferCounter); ok {
+		counter.retries.Add(1)
+	}
+}
@@ -0,0 +1,36 @@
+package s3
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// NewTagging returns a Tagging with a tag for every entry, ordered by key.
+func NewTagging(tags map[string]string) Tagging {
+	var tagging Tagging
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		tagging.TagSet.Tags = append(tagging.TagSet.Tags, Tag{Key: key, Value: tags[key]})
+	}
+	return tagging
+}
+
+// ToMap returns the tags keyed by their keys.
+func (t *Tagging) ToMap() map[string]string {
+	tags := make(map[string]string, len(t.TagSet.Tags))
+	for _, tag := range t.TagSet.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	return tags
+}
+
+// encodeTags returns the tags URL encoded as expected in the x-amz-tagging header.
+func encodeTags(tags map[string]string) string {
+	values := make(url.Values, len(tags))
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	// spaces are sent as %20, a literal + is already escaped as %2B
+	return strings.ReplaceAll(values.Encode(), "+", "%20")
+}
@@ -0,0 +1,31 @@
+package s3
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestEncodeTags(t *testing.T) {
+	tags := map[string]string{
+		"project name": "a+b",
+		"query":        "x=1&y=2",
+		"plain":        "value",
+	}
+
+	got := encodeTags(tags)
+	want := "plain=value&project%20name=a%2Bb&query=x%3D1%26y%3D2"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// S3 decodes the header like a query string
+	decoded, err := url.ParseQuery(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range tags {
+		if decoded.Get(key) != value {
+			t.Errorf("tag %q decoded to %q, want %q", key, decoded.Get(key), value)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package s3
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// size of the data in a single aws-chunked frame
+const trailerChunkSize = 64 * 1024
+
+// trailingChecksumReader encodes a body of known size as aws-chunked frames
+// followed by a trailer with the checksum of the data, so the checksum is
+// computed while the body is sent.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html#sigv4-chunked-body-definition
+type trailingChecksumReader struct {
+	src       io.Reader
+	remaining int64
+	hash      hash.Hash
+	trailer   string
+	buf       []byte
+	pending   []byte
+	done      bool
+}
+
+func newTrailingChecksumReader(src io.Reader, size int64, algorithm string) (*trailingChecksumReader, error) {
+	h, err := newChecksumHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &trailingChecksumReader{
+		src:       src,
+		remaining: size,
+		hash:      h,
+		trailer:   trailerHeader(algorithm),
+		buf:       make([]byte, trailerChunkSize),
+	}, nil
+}
+
+// trailerHeader returns the name of the trailing checksum header for an algorithm.
+func trailerHeader(algorithm string) string {
+	return "x-amz-checksum-" + strings.ToLower(algorithm)
+}
+
+func (r *trailingChecksumReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.nextFrame(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// nextFrame encodes the next chunk of data, or the final empty chunk and the
+// trailer once all data was read.
+func (r *trailingChecksumReader) nextFrame() error {
+	if r.remaining == 0 {
+		r.done = true
+		r.pending = []byte("0\r\n" + r.trailer + ":" + EncodeChecksum(r.hash) + "\r\n\r\n")
+		return nil
+	}
+
+	chunk := r.buf[:min(int64(len(r.buf)), r.remaining)]
+	if _, err := io.ReadFull(r.src, chunk); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	r.remaining -= int64(len(chunk))
+	r.hash.Write(chunk)
+
+	frame := make([]byte, 0, len(chunk)+16)
+	frame = strconv.AppendInt(frame, int64(len(chunk)), 16)
+	frame = append(frame, "\r\n"...)
+	frame = append(frame, chunk...)
+	r.pending = append(frame, "\r\n"...)
+	return nil
+}
+
+// encodedLength returns the length of the aws-chunked encoding of size bytes
+// with a trailing checksum of the given algorithm.
+func (r *trailingChecksumReader) encodedLength(size int64) int64 {
+	frameLength := func(n int64) int64 {
+		return int64(len(strconv.FormatInt(n, 16))) + 2 + n + 2
+	}
+
+	length := size / trailerChunkSize * frameLength(trailerChunkSize)
+	if rest := size % trailerChunkSize; rest > 0 {
+		length += frameLength(rest)
+	}
+	checksumLength := base64.StdEncoding.EncodedLen(r.hash.Size())
+	return length + int64(len("0\r\n")+len(r.trailer)+1+checksumLength+len("\r\n\r\n"))
+}
+
+// checksum returns the base64 encoded checksum of the data read so far.
+func (r *trailingChecksumReader) checksum() string {
+	return EncodeChecksum(r.hash)
+}
+
+// setTrailerHeaders declares the aws-chunked encoding of a body of size bytes
+// and the checksum trailer sent after it.
+func setTrailerHeaders(req *http.Request, trailer *trailingChecksumReader, size int64) {
+	req.Header.Set("x-amz-content-sha256", "STREAMING-UNSIGNED-PAYLOAD-TRAILER")
+	req.Header.Set("Content-Encoding", "aws-chunked")
+	req.Header.Set("x-amz-decoded-content-length", strconv.FormatInt(size, 10))
+	req.Header.Set("x-amz-trailer", trailer.trailer)
+	req.ContentLength = trailer.encodedLength(size)
+}
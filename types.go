@@ -1,10 +1,14 @@
 package s3
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,10 +22,47 @@ type Config struct {
 	AccessKey string
 	// S3 Secret Access key
 	SecretKey string
+	// Session token of temporary credentials, e.g. issued by STS
+	SessionToken string
 	// S3 region
 	Region string
 	// Endpoint is URL to the s3 service.
 	Endpoint string
+	// PathStyle addresses buckets in the path (host/bucket/key) instead of
+	// as a subdomain (bucket.host/key), as required by MinIO and most S3 gateways
+	PathStyle bool
+	// Number of times a failed idempotent request is retried, 0 disables retries
+	MaxRetries int
+	// Delay before the first retry, doubled with every further attempt
+	RetryBaseDelay time.Duration
+	// RetryableFunc decides whether a failed request is retried, DefaultRetryable
+	// if nil. resp is nil when no response was received. Requests that are not
+	// idempotent or whose body can not be rewound are never retried.
+	RetryableFunc func(req *http.Request, resp *http.Response, err error) bool
+	// Default part size of multipart uploads and ranged downloads, 8 MiB if 0
+	MultipartPartSize int64
+	// Size from which Upload switches to a multipart upload, 16 MiB if 0
+	MultipartThreshold int64
+	// Maximum number of bytes read from a streamed request body at once, 64 KiB if 0.
+	// Small chunks cause many small writes, which is slow on WASI transports.
+	ChunkSize int
+	// UseServerTime signs requests with the server time derived from the Date
+	// header of previous responses instead of the local clock, see ServerTime.
+	// This keeps requests valid on hosts with a skewed clock.
+	UseServerTime bool
+	// Clock returns the local time used for signatures and the server time
+	// estimate, time.Now if nil. Tests can pin a fixed time with it.
+	Clock func() time.Time
+	// Service name in the credential scope of signatures, "s3" if empty.
+	// Some S3 compatible services expect a provider specific name.
+	SigningService string
+	// RequestHook is called after every attempt of a request, e.g. to log,
+	// trace or count requests. It must not retain or modify the request.
+	RequestHook func(ctx context.Context, info RequestInfo)
+	// Checksum algorithm of the DeleteObjects request body, sent alongside
+	// Content-MD5, CRC32 if empty. "MD5" sends only Content-MD5 for services
+	// that do not support flexible checksums.
+	DeleteChecksumAlgorithm string
 }
 
 // Client provides an interface for interacting with the S3 API.
@@ -29,6 +70,21 @@ type Client struct {
 	config      Config
 	endpointURL string
 	httpClient  *http.Client
+
+	// signing regions of buckets outside the configured region
+	regionsMu sync.RWMutex
+	regions   map[string]string
+
+	// set once the service rejected GetObjectAttributes as not implemented
+	attributesUnsupported atomic.Bool
+
+	// server time of the last response and the local time it was received
+	clockMu            sync.Mutex
+	serverDate         time.Time
+	serverDateReceived time.Time
+	// set once a request was rejected for clock skew, from then on requests
+	// are signed with the server time
+	skewCorrected atomic.Bool
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateMultipartUpload.html#AmazonS3-CreateMultipartUpload-response-CreateMultipartUploadOutput
@@ -53,16 +109,38 @@ type CompletedPart struct {
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_CompletedMultipartUpload.html
 type CompleteMultipartUpload struct {
-	XmlName xml.Name        `xml:"CompleteMultipartUpload"`
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
 	Parts   []CompletedPart `xml:"Part"`
 }
 
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CompleteMultipartUpload.html#API_CompleteMultipartUpload_ResponseSyntax
+type CompleteMultipartUploadResult struct {
+	XMLName           xml.Name `xml:"CompleteMultipartUploadResult"`
+	Location          string   `xml:"Location"`
+	Bucket            string   `xml:"Bucket"`
+	Key               string   `xml:"Key"`
+	ETag              string   `xml:"ETag"`
+	ChecksumCRC32     string   `xml:"ChecksumCRC32"`
+	ChecksumCRC32C    string   `xml:"ChecksumCRC32C"`
+	ChecksumCRC64NVME string   `xml:"ChecksumCRC64NVME"`
+	ChecksumSHA1      string   `xml:"ChecksumSHA1"`
+	ChecksumSHA256    string   `xml:"ChecksumSHA256"`
+	ChecksumType      string   `xml:"ChecksumType"`
+	VersionId         string   `xml:"-"`
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListBuckets.html#API_ListBuckets_ResponseSyntax
 type ListBucketsResponse struct {
 	Buckets []BucketInfo `xml:"Buckets>Bucket"`
 	Owner   Owner
 }
 
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLocation.html#API_GetBucketLocation_ResponseSyntax
+type LocationConstraint struct {
+	XMLName xml.Name `xml:"LocationConstraint"`
+	Region  string   `xml:",chardata"`
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_Bucket.html
 type BucketInfo struct {
 	Name         string
@@ -70,17 +148,22 @@ type BucketInfo struct {
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjects.html#API_ListObjects_ResponseSyntax
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectsV2.html#API_ListObjectsV2_ResponseSyntax
 type ListObjectsResponse struct {
-	CommonPrefixes []CommonPrefix
-	Contents       []ObjectInfo
-	Delimiter      string
-	EncodingType   string
-	IsTruncated    bool
-	Marker         string
-	MaxKeys        int
-	Name           string
-	NextMarker     string
-	Prefix         string
+	CommonPrefixes        []CommonPrefix
+	Contents              []ObjectInfo
+	Delimiter             string
+	EncodingType          string
+	IsTruncated           bool
+	Marker                string
+	MaxKeys               int
+	Name                  string
+	NextMarker            string
+	Prefix                string
+	ContinuationToken     string
+	NextContinuationToken string
+	KeyCount              int
+	StartAfter            string
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_CommonPrefix.html
@@ -104,6 +187,8 @@ type ErrorResponse struct {
 	Message   string `xml:"Message"`
 	Resource  string `xml:"Resource"`
 	RequestID string `xml:"RequestId"`
+	// HTTP status code of the response
+	StatusCode int `xml:"-"`
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListMultipartUploads.html#AmazonS3-ListMultipartUploads-response-ListMultipartUploadsOutput
@@ -173,6 +258,29 @@ type Part struct {
 	Size              int64  `xml:"Size"`
 }
 
+// CompletedParts returns the listed parts with their ETags and checksums as
+// needed by CompleteMultipartUpload.
+func (r ListPartsResult) CompletedParts() []CompletedPart {
+	parts := make([]CompletedPart, 0, len(r.Parts))
+	for _, part := range r.Parts {
+		parts = append(parts, part.completedPart())
+	}
+	return parts
+}
+
+// completedPart maps a listed part to a part of a completion request.
+func (p Part) completedPart() CompletedPart {
+	return CompletedPart{
+		PartNumber:        p.PartNumber,
+		ETag:              p.ETag,
+		ChecksumCRC32:     p.ChecksumCRC32,
+		ChecksumCRC32C:    p.ChecksumCRC32C,
+		ChecksumCRC64NVME: p.ChecksumCRC64NVME,
+		ChecksumSHA1:      p.ChecksumSHA1,
+		ChecksumSHA256:    p.ChecksumSHA256,
+	}
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_ObjectPart.html
 type ObjectPart struct {
 	ChecksumCRC32     string `xml:"ChecksumCRC32"`
@@ -194,6 +302,8 @@ type GetObjectAttributesResponse struct {
 	ObjectAttributeParts GetObjectAttributesParts `xml:"ObjectParts"`
 	StorageClass         string                   `xml:"StorageClass"`
 	ObjectSize           int64                    `xml:"ObjectSize"`
+	LastModified         time.Time                `xml:"-"`
+	VersionId            string                   `xml:"-"`
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_Checksum.html
@@ -256,6 +366,22 @@ type WebsiteConfiguration struct {
 	RoutingRules          []RoutingRule          `xml:"RoutingRules>RoutingRule"`
 }
 
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketCors.html#AmazonS3-GetBucketCors-response-CORSRules
+type CORSConfiguration struct {
+	XMLName   xml.Name   `xml:"CORSConfiguration"`
+	CORSRules []CORSRule `xml:"CORSRule"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CORSRule.html
+type CORSRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  int      `xml:"MaxAgeSeconds,omitempty"`
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_RedirectAllRequestsTo.html
 type RedirectAllRequestsTo struct {
 	HostName string `xml:"HostName"`
@@ -302,11 +428,11 @@ type Delete struct {
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_ObjectIdentifier.html
 type ObjectIdentifier struct {
-	ETag             string    `xml:"ETag"`
-	Key              string    `xml:"Key"`
-	LastModifiedTime time.Time `xml:"LastModifiedTime"`
-	Size             int64     `xml:"Size"`
-	VersionId        string    `xml:"VersionId"`
+	ETag             string     `xml:"ETag,omitempty"`
+	Key              string     `xml:"Key"`
+	LastModifiedTime *time.Time `xml:"LastModifiedTime,omitempty"`
+	Size             int64      `xml:"Size,omitempty"`
+	VersionId        string     `xml:"VersionId,omitempty"`
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObjects.html#AmazonS3-DeleteObjects-response-DeleteObjectsOutput
@@ -332,6 +458,21 @@ type Error struct {
 	VersionId string `xml:"VersionId"`
 }
 
+// Parameters of ListObjectsV2WithInput
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectsV2.html#API_ListObjectsV2_RequestSyntax
+type ListObjectsV2Input struct {
+	Prefix    string
+	Delimiter string
+	// Maximum number of keys per page, 1000 if 0
+	MaxKeys           int
+	StartAfter        string
+	ContinuationToken string
+	// FetchOwner includes the owner of every object
+	FetchOwner bool
+	// "url" to list keys that contain characters invalid in XML, the keys are decoded
+	EncodingType string
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectVersions.html#AmazonS3-ListObjectVersions-response-ListObjectVersionsOutput
 type ListVersionsResult struct {
 	XMLName             xml.Name            `xml:"ListVersionsResult"`
@@ -393,6 +534,24 @@ type VersioningConfiguration struct {
 	MfaDelete string   `xml:"MfaDelete"`
 }
 
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ServerSideEncryptionConfiguration.html
+type ServerSideEncryptionConfiguration struct {
+	XMLName xml.Name                   `xml:"ServerSideEncryptionConfiguration"`
+	Rules   []ServerSideEncryptionRule `xml:"Rule"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ServerSideEncryptionRule.html
+type ServerSideEncryptionRule struct {
+	ApplyServerSideEncryptionByDefault *ServerSideEncryptionByDefault `xml:"ApplyServerSideEncryptionByDefault"`
+	BucketKeyEnabled                   bool                           `xml:"BucketKeyEnabled"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ServerSideEncryptionByDefault.html
+type ServerSideEncryptionByDefault struct {
+	SSEAlgorithm   string `xml:"SSEAlgorithm"`
+	KMSMasterKeyID string `xml:"KMSMasterKeyID,omitempty"`
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectLockConfiguration.html#AmazonS3-GetObjectLockConfiguration-response-ObjectLockConfigurationhttps://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectLockConfiguration.html#AmazonS3-GetObjectLockConfiguration-response-ObjectLockConfiguration
 type ObjectLockConfiguration struct {
 	XMLName           xml.Name        `xml:"ObjectLockConfiguration"`
@@ -487,10 +646,10 @@ type Grantee struct {
 	XMLName      xml.Name `xml:"Grantee"`
 	XmlnsXsi     string   `xml:"xmlns:xsi,attr"`
 	XsiType      string   `xml:"xsi:type,attr"`
-	ID           string   `xml:"ID"`
-	DisplayName  string   `xml:"DisplayName"`
-	URI          string   `xml:"URI"`
-	EmailAddress string   `xml:"EmailAddress"`
+	ID           string   `xml:"ID,omitempty"`
+	DisplayName  string   `xml:"DisplayName,omitempty"`
+	URI          string   `xml:"URI,omitempty"`
+	EmailAddress string   `xml:"EmailAddress,omitempty"`
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLifecycle.html#AmazonS3-GetBucketLifecycle-response-GetBucketLifecycleOutput
@@ -708,4 +867,178 @@ type S3TablesDestinationResult struct {
 
 type PutObjectMetadata struct {
 	ContentLength int64
+	// Content-Type of the object, derived from the key extension if empty
+	ContentType string
+	// Server-side encryption algorithm, e.g. AES256 or aws:kms
+	ServerSideEncryption string
+	// KMS key used with aws:kms encryption, the bucket's default key if empty
+	SSEKMSKeyId string
+	// User-defined metadata, sent as x-amz-meta-<key> headers
+	UserMetadata map[string]string
+	// Storage class of the object, e.g. STANDARD_IA or GLACIER
+	StorageClass string
+	// Tags of the object, sent URL encoded as x-amz-tagging header
+	Tags map[string]string
+	// ChecksumAlgorithm computes a checksum of the given algorithm (CRC32,
+	// CRC32C, CRC64NVME, SHA1 or SHA256) while streaming and sends it as
+	// trailer, like WithChecksum(algorithm, ""). ContentLength must be set.
+	ChecksumAlgorithm string
+	// VerifyMD5 sends the MD5 digest of the body, like WithContentMD5, so the
+	// service rejects uploads corrupted in transit. The body must implement
+	// io.ReadSeeker, as it is read once to compute the digest.
+	VerifyMD5 bool
+}
+
+// Range is an inclusive byte range of an object.
+// A negative End reads from Start to the end of the object, a negative Start
+// reads the last -Start bytes of the object and ignores End.
+type Range struct {
+	Start int64
+	End   int64
+}
+
+// String returns the value of the Range header, e.g. "bytes=0-99", "bytes=100-" or "bytes=-100".
+func (r Range) String() string {
+	switch {
+	case r.Start < 0:
+		return fmt.Sprintf("bytes=%d", r.Start)
+	case r.End < 0:
+		return fmt.Sprintf("bytes=%d-", r.Start)
+	default:
+		return fmt.Sprintf("bytes=%d-%d", r.Start, r.End)
+	}
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadObject.html#API_HeadObject_ResponseSyntax
+type HeadObjectResult struct {
+	ContentLength      int64
+	ContentType        string
+	CacheControl       string
+	ContentDisposition string
+	ContentEncoding    string
+	ContentLanguage    string
+	ETag               string
+	LastModified       time.Time
+	VersionId          string
+	TaggingCount       int
+	// Storage class of the object, empty for STANDARD
+	StorageClass string
+	// Number of parts of a multipart object, only returned when a part number is requested
+	PartsCount int
+	// User-defined metadata from the x-amz-meta-* headers, keyed by lowercase name without prefix
+	Metadata map[string]string
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html#API_GetObject_ResponseSyntax
+type GetObjectResult struct {
+	HeadObjectResult
+	Body io.ReadCloser
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObject.html#API_PutObject_ResponseSyntax
+type PutObjectResult struct {
+	ETag                 string
+	VersionId            string
+	ServerSideEncryption string
+	Checksum             Checksum
+	// Stats of the transfer, only set by Upload and UploadLarge, also on failure
+	Stats TransferStats
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObject.html#API_PutObject_ResponseSyntax
+type AppendObjectResult struct {
+	ETag string
+	// Total size of the object after the append, the offset for the next append
+	ObjectSize int64
+	Checksum   Checksum
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CopyObject.html#API_CopyObject_RequestSyntax
+type CopyOptions struct {
+	// Version of the source object to copy, the current version if empty
+	SourceVersionId string
+	// Condition on the destination, "*" fails the copy if the destination already exists
+	IfNoneMatch string
+	// COPY keeps the metadata of the source, REPLACE uses the metadata of the request
+	MetadataDirective string
+	// Content-Type of the copy, only applied with the REPLACE directive
+	ContentType string
+	// SSE-C key the source object is encrypted with
+	SourceSSECustomerKey []byte
+	// SSE-C key to encrypt the copy with
+	SSECustomerKey []byte
+	// Options applied to the copy like to an upload, e.g. WithSSE,
+	// WithStorageClass or WithRequestPayer; the fields above take precedence
+	// and WithVersionID is ignored in favor of SourceVersionId
+	ObjectOptions []ObjectOption
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CopyPartResult.html
+type CopyPartResult struct {
+	XMLName           xml.Name  `xml:"CopyPartResult"`
+	ETag              string    `xml:"ETag"`
+	LastModified      time.Time `xml:"LastModified"`
+	ChecksumCRC32     string    `xml:"ChecksumCRC32"`
+	ChecksumCRC32C    string    `xml:"ChecksumCRC32C"`
+	ChecksumCRC64NVME string    `xml:"ChecksumCRC64NVME"`
+	ChecksumSHA1      string    `xml:"ChecksumSHA1"`
+	ChecksumSHA256    string    `xml:"ChecksumSHA256"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CopyObjectResult.html
+type CopyObjectResult struct {
+	XMLName             xml.Name  `xml:"CopyObjectResult"`
+	ETag                string    `xml:"ETag"`
+	LastModified        time.Time `xml:"LastModified"`
+	ChecksumCRC32       string    `xml:"ChecksumCRC32"`
+	ChecksumCRC32C      string    `xml:"ChecksumCRC32C"`
+	ChecksumCRC64NVME   string    `xml:"ChecksumCRC64NVME"`
+	ChecksumSHA1        string    `xml:"ChecksumSHA1"`
+	ChecksumSHA256      string    `xml:"ChecksumSHA256"`
+	VersionId           string    `xml:"-"`
+	CopySourceVersionId string    `xml:"-"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ReplicationConfiguration.html
+type ReplicationConfiguration struct {
+	XMLName xml.Name          `xml:"ReplicationConfiguration"`
+	Role    string            `xml:"Role"`
+	Rules   []ReplicationRule `xml:"Rule"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ReplicationRule.html
+type ReplicationRule struct {
+	ID                      string                   `xml:"ID,omitempty"`
+	Priority                int                      `xml:"Priority,omitempty"`
+	Status                  string                   `xml:"Status"`
+	Filter                  *ReplicationRuleFilter   `xml:"Filter"`
+	Prefix                  string                   `xml:"Prefix,omitempty"`
+	Destination             ReplicationDestination   `xml:"Destination"`
+	DeleteMarkerReplication *DeleteMarkerReplication `xml:"DeleteMarkerReplication"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ReplicationRuleFilter.html
+type ReplicationRuleFilter struct {
+	Prefix string                      `xml:"Prefix,omitempty"`
+	Tag    *Tag                        `xml:"Tag"`
+	And    *ReplicationRuleAndOperator `xml:"And"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_ReplicationRuleAndOperator.html
+type ReplicationRuleAndOperator struct {
+	Prefix string `xml:"Prefix,omitempty"`
+	Tags   []Tag  `xml:"Tag"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_Destination.html
+type ReplicationDestination struct {
+	// ARN of the destination bucket
+	Bucket       string `xml:"Bucket"`
+	Account      string `xml:"Account,omitempty"`
+	StorageClass string `xml:"StorageClass,omitempty"`
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteMarkerReplication.html
+type DeleteMarkerReplication struct {
+	Status string `xml:"Status"`
 }
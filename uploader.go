@@ -0,0 +1,368 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// default part size used for multipart uploads and ranged downloads
+const defaultPartSize = 8 * 1024 * 1024
+
+// default size from which Upload uses a multipart upload
+const defaultMultipartThreshold = 16 * 1024 * 1024
+
+// smallest part size accepted by S3 for all but the last part
+const minUploadPartSize = 5 * 1024 * 1024
+
+// highest part number of a multipart upload
+const maxPartNumber = 10000
+
+// retries of CreateMultipartUpload in UploadLarge if MaxRetries is lower
+const defaultCreateUploadRetries = 2
+
+// MultipartOptions contains the available options for UploadLarge.
+type MultipartOptions struct {
+	// Size of a single part in bytes, at least 5 MiB
+	PartSize int64
+	// Number of parts uploaded in parallel at the start of an upload, each buffered in memory
+	Concurrency int
+	// Lower bound the concurrency is reduced to when the service responds with SlowDown
+	MinConcurrency int
+	// Upper bound the concurrency ramps up to after sustained successes,
+	// Concurrency if lower; it bounds the number of buffered parts
+	MaxConcurrency int
+	// FullObjectChecksum computes the CRC64NVME checksum of the whole object
+	// while uploading and has the service verify it on completion. Objects
+	// Upload sends in a single request carry it as trailing checksum.
+	FullObjectChecksum bool
+	// ChecksumAlgorithm sends a checksum of the given algorithm (CRC32, CRC32C,
+	// SHA1 or SHA256) with every part, or with the single request of a small
+	// Upload; it can not be combined with FullObjectChecksum
+	ChecksumAlgorithm string
+	// Options applied when the upload is created, e.g. WithContentType or WithSSE
+	ObjectOptions []ObjectOption
+}
+
+// Upload uploads the content of r with a single PutObjectStream request if
+// its size is known and below the configured multipart threshold, and with
+// UploadLarge otherwise. A negative size marks an unknown size.
+// The result carries the ETag, the stats of the transfer and, in versioned
+// buckets, the version id of the created object. If the upload fails, a
+// result carrying only the stats of the transfer so far is returned with the
+// error.
+func (c *Client) Upload(ctx context.Context, bucketName, objectName string, r io.Reader, size int64, opts *MultipartOptions) (*PutObjectResult, error) {
+	ctx, counter := startTransfer(ctx)
+
+	options, err := c.multipartOptions(opts)
+	if err != nil {
+		return &PutObjectResult{Stats: counter.stats(size)}, err
+	}
+	if size < 0 || size >= c.multipartThreshold() {
+		return c.UploadLarge(ctx, bucketName, objectName, r, &options)
+	}
+
+	// a single request carries the requested checksum of the whole object
+	checksumAlgorithm := options.ChecksumAlgorithm
+	if options.FullObjectChecksum {
+		checksumAlgorithm = "CRC64NVME"
+	}
+	if size == 0 {
+		objectOpts := options.ObjectOptions
+		if checksumAlgorithm != "" {
+			objectOpts = append(slices.Clone(objectOpts), WithChecksum(checksumAlgorithm, ""))
+		}
+		result, err := c.PutObjectWithInfo(ctx, bucketName, objectName, nil, objectOpts...)
+		if err != nil {
+			return &PutObjectResult{Stats: counter.stats(size)}, err
+		}
+		countTransferred(ctx, 0, 1)
+		result.Stats = counter.stats(0)
+		return result, nil
+	}
+	metadata := &PutObjectMetadata{ContentLength: size, ChecksumAlgorithm: checksumAlgorithm}
+	resp, err := c.PutObjectStream(ctx, bucketName, objectName, r, metadata, options.ObjectOptions...)
+	if err != nil {
+		return &PutObjectResult{Stats: counter.stats(size)}, err
+	}
+	resp.Body.Close()
+	countTransferred(ctx, size, 1)
+
+	result := parsePutObjectResult(resp)
+	result.Stats = counter.stats(size)
+	return &result, nil
+}
+
+// UploadLarge uploads the content of r as a multipart upload. The reader is
+// split into parts that are uploaded with the configured concurrency.
+// On any error the multipart upload is aborted, and a result carrying only
+// the stats of the transfer so far is returned with the error.
+func (c *Client) UploadLarge(ctx context.Context, bucketName, objectName string, r io.Reader, opts *MultipartOptions) (*PutObjectResult, error) {
+	ctx, counter := startTransfer(ctx)
+
+	options, err := c.multipartOptions(opts)
+	if err != nil {
+		return &PutObjectResult{Stats: counter.stats(counter.bytes.Load())}, err
+	}
+
+	createOpts := slices.Clone(options.ObjectOptions)
+	if options.FullObjectChecksum {
+		createOpts = append(createOpts, WithChecksum("CRC64NVME", ""))
+	}
+	if options.ChecksumAlgorithm != "" {
+		createOpts = append(createOpts, WithChecksum(options.ChecksumAlgorithm, ""))
+	}
+	upload, err := c.createMultipartUpload(ctx, bucketName, objectName, createOpts)
+	if err != nil {
+		return &PutObjectResult{Stats: counter.stats(counter.bytes.Load())}, err
+	}
+
+	result, err := c.uploadLarge(ctx, bucketName, objectName, upload.UploadId, r, &options)
+	if err != nil {
+		// abort even if ctx is done, so no parts are left behind
+		if abortErr := c.AbortMultipartUpload(context.WithoutCancel(ctx), bucketName, objectName, upload.UploadId); abortErr != nil {
+			return &PutObjectResult{Stats: counter.stats(counter.bytes.Load())}, errors.Join(err, fmt.Errorf("failed to abort upload: %w", abortErr))
+		}
+		return &PutObjectResult{Stats: counter.stats(counter.bytes.Load())}, err
+	}
+
+	return &PutObjectResult{
+		ETag:      result.ETag,
+		VersionId: result.VersionId,
+		Checksum: Checksum{
+			ChecksumCRC32:     result.ChecksumCRC32,
+			ChecksumCRC32C:    result.ChecksumCRC32C,
+			ChecksumCRC64NVME: result.ChecksumCRC64NVME,
+			ChecksumSHA1:      result.ChecksumSHA1,
+			ChecksumSHA256:    result.ChecksumSHA256,
+			ChecksumType:      result.ChecksumType,
+		},
+		Stats: counter.stats(counter.bytes.Load()),
+	}, nil
+}
+
+// multipartOptions returns a copy of opts with the defaults applied and
+// validates it.
+func (c *Client) multipartOptions(opts *MultipartOptions) (MultipartOptions, error) {
+	var options MultipartOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.PartSize <= 0 {
+		options.PartSize = c.partSize()
+	}
+	if options.PartSize < minUploadPartSize {
+		return options, fmt.Errorf("part size must be at least %d bytes, got %d", minUploadPartSize, options.PartSize)
+	}
+	options.Concurrency = max(options.Concurrency, 1)
+	options.MinConcurrency = max(options.MinConcurrency, 1)
+	options.MaxConcurrency = max(options.MaxConcurrency, options.Concurrency)
+	if options.FullObjectChecksum && options.ChecksumAlgorithm != "" {
+		return options, fmt.Errorf("full object checksum can not be combined with part checksums")
+	}
+	return options, nil
+}
+
+// createMultipartUpload creates a multipart upload and retries on server
+// errors, throttling and network failures. An attempt whose response was lost
+// may still have created an upload, so once a retry succeeds the uploads of
+// objectName initiated while a failed attempt was in flight are aborted.
+func (c *Client) createMultipartUpload(ctx context.Context, bucketName, objectName string, opts []ObjectOption) (*InitiateMultipartUploadResult, error) {
+	retries := max(c.config.MaxRetries, defaultCreateUploadRetries)
+	var failed []attemptWindow
+
+	for attempt := 0; ; attempt++ {
+		started := c.ServerTime()
+		upload, err := c.CreateMultipartUpload(ctx, bucketName, objectName, opts...)
+		if err == nil {
+			if len(failed) > 0 {
+				c.abortOrphanedUploads(ctx, bucketName, objectName, upload.UploadId, failed)
+			}
+			return upload, nil
+		}
+		failed = append(failed, attemptWindow{start: started, end: c.ServerTime()})
+		if attempt >= retries || ctx.Err() != nil || !isTransient(err) {
+			return nil, fmt.Errorf("failed to create multipart upload: %w", err)
+		}
+		if err := sleepContext(ctx, c.retryDelay(attempt)); err != nil {
+			return nil, fmt.Errorf("failed to create multipart upload: %w", err)
+		}
+		countRetry(ctx)
+	}
+}
+
+// attemptWindow is the time, as seen by the service, a request was in flight.
+type attemptWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// contains reports whether t lies in the window. The service reports times
+// with second precision, so the window is widened to whole seconds.
+func (w attemptWindow) contains(t time.Time) bool {
+	start := w.start.Truncate(time.Second)
+	end := w.end.Truncate(time.Second).Add(time.Second)
+	return !t.Before(start) && t.Before(end)
+}
+
+// abortOrphanedUploads aborts the uploads of exactly objectName, except
+// uploadId, that were initiated within one of the windows of failed attempts.
+// Uploads of other writers started outside these windows are left alone.
+// Errors are ignored, the orphans are only cleaned up on a best-effort basis.
+func (c *Client) abortOrphanedUploads(ctx context.Context, bucketName, objectName, uploadId string, failed []attemptWindow) {
+	query := make(map[string]string)
+	query["prefix"] = objectName
+
+	uploads, err := c.ListMultipartUploads(ctx, bucketName, query)
+	if err != nil {
+		return
+	}
+	for _, upload := range uploads.Uploads {
+		if upload.Key != objectName || upload.UploadId == uploadId {
+			continue
+		}
+		initiated, err := time.Parse(time.RFC3339, upload.Initiated)
+		if err != nil {
+			continue
+		}
+		if slices.ContainsFunc(failed, func(w attemptWindow) bool { return w.contains(initiated) }) {
+			c.AbortMultipartUpload(ctx, bucketName, objectName, upload.UploadId)
+		}
+	}
+}
+
+// uploadLarge uploads all parts and completes the upload.
+func (c *Client) uploadLarge(ctx context.Context, bucketName, objectName, uploadId string, r io.Reader, options *MultipartOptions) (*CompleteMultipartUploadResult, error) {
+	checksum := NewCRC64NVME()
+	if options.FullObjectChecksum {
+		r = io.TeeReader(r, checksum)
+	}
+
+	parts, err := c.uploadParts(ctx, bucketName, objectName, uploadId, r, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.FullObjectChecksum {
+		return c.CompleteMultipartUploadWithChecksum(ctx, bucketName, objectName, uploadId, parts, EncodeChecksum(checksum))
+	}
+	return c.CompleteMultipartUpload(ctx, bucketName, objectName, uploadId, parts)
+}
+
+// uploadParts reads r part by part and uploads the parts with adaptive
+// concurrency. The completed parts are returned ordered by part number.
+func (c *Client) uploadParts(ctx context.Context, bucketName, objectName, uploadId string, r io.Reader, options *MultipartOptions) ([]CompletedPart, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		parts    []CompletedPart
+	)
+	limiter := newAdaptiveLimiter(options.Concurrency, options.MinConcurrency, options.MaxConcurrency)
+
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	for partNumber := 1; ; partNumber++ {
+		// a slot is taken before reading, so at most MaxConcurrency parts are buffered
+		if err := limiter.acquire(ctx); err != nil {
+			fail(err)
+			break
+		}
+
+		buf := make([]byte, options.PartSize)
+		n, err := io.ReadFull(r, buf)
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			limiter.release(nil)
+			fail(fmt.Errorf("failed to read part %d: %w", partNumber, err))
+			break
+		}
+		if n == 0 && partNumber > 1 {
+			limiter.release(nil)
+			break
+		}
+
+		wg.Add(1)
+		go func(partNumber int, data []byte) {
+			defer wg.Done()
+			part, err := c.uploadPart(ctx, bucketName, objectName, data, partNumber, uploadId, options.ChecksumAlgorithm, options.ObjectOptions)
+			limiter.release(err)
+			if err != nil {
+				fail(fmt.Errorf("failed to upload part %d: %w", partNumber, err))
+				return
+			}
+
+			countTransferred(ctx, int64(len(data)), 1)
+
+			mu.Lock()
+			parts = append(parts, *part)
+			mu.Unlock()
+		}(partNumber, buf[:n])
+
+		if last {
+			break
+		}
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	slices.SortFunc(parts, func(a, b CompletedPart) int {
+		return a.PartNumber - b.PartNumber
+	})
+	return parts, nil
+}
+
+// uploadPart uploads a single part, with a checksum if an algorithm is given.
+// Of opts only the options that apply to parts, e.g. the SSE-C key, are sent.
+func (c *Client) uploadPart(ctx context.Context, bucketName, objectName string, data []byte, partNumber int, uploadId, algorithm string, opts []ObjectOption) (*CompletedPart, error) {
+	if algorithm != "" {
+		return c.UploadPartWithChecksum(ctx, bucketName, objectName, data, partNumber, uploadId, algorithm, opts...)
+	}
+
+	// parts of locked uploads must carry an integrity check
+	if newObjectOptions(opts).objectLocked() {
+		opts = append(slices.Clone(opts), WithContentMD5())
+	}
+	return c.UploadPart(ctx, bucketName, objectName, bytes.NewReader(data), uint64(len(data)), uint64(partNumber), uploadId, opts...)
+}
+
+// partSize returns the configured default part size.
+func (c *Client) partSize() int64 {
+	if c.config.MultipartPartSize > 0 {
+		return c.config.MultipartPartSize
+	}
+	return defaultPartSize
+}
+
+// multipartThreshold returns the configured size from which uploads use multipart.
+func (c *Client) multipartThreshold() int64 {
+	if c.config.MultipartThreshold > 0 {
+		return c.config.MultipartThreshold
+	}
+	return defaultMultipartThreshold
+}
+
+// checkPartNumber returns an error if partNumber is outside of 1 to 10000.
+func checkPartNumber(partNumber int64) error {
+	if partNumber < 1 || partNumber > maxPartNumber {
+		return fmt.Errorf("part number must be between 1 and %d, got %d", maxPartNumber, partNumber)
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// multipartServer is a minimal multipart upload endpoint that records the
+// headers of every uploaded part, the completion request and aborts. Parts
+// numbered failPart are rejected.
+type multipartServer struct {
+	mu          sync.Mutex
+	partHeaders map[string]http.Header
+	failPart    string
+	completion  string
+	aborted     bool
+}
+
+func (s *multipartServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>key</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		if query.Get("partNumber") == s.failPart {
+			http.Error(w, "", http.StatusForbidden)
+			return
+		}
+		s.mu.Lock()
+		s.partHeaders[query.Get("partNumber")] = r.Header.Clone()
+		s.mu.Unlock()
+		w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		s.completion = string(body)
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"etag-2"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete:
+		s.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestUploadLargeSendsSSECustomerKeyWithParts(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	for _, algorithm := range []string{"", "CRC32"} {
+		t.Run("checksum="+algorithm, func(t *testing.T) {
+			server := &multipartServer{partHeaders: make(map[string]http.Header)}
+			client := newTestClient(t, server)
+
+			data := bytes.Repeat([]byte("a"), minUploadPartSize+1)
+			_, err := client.UploadLarge(context.Background(), "bucket", "key", bytes.NewReader(data), &MultipartOptions{
+				PartSize:          minUploadPartSize,
+				ChecksumAlgorithm: algorithm,
+				ObjectOptions:     []ObjectOption{WithSSECustomerKey(key)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(server.partHeaders) != 2 {
+				t.Fatalf("got %d parts, want 2", len(server.partHeaders))
+			}
+			want := http.Header{}
+			setSSECustomerHeaders(&http.Request{Header: want}, "x-amz-server-side-encryption-customer-", key)
+			for partNumber, header := range server.partHeaders {
+				for name := range want {
+					if got := header.Get(name); got != want.Get(name) {
+						t.Errorf("part %s: %s = %q, want %q", partNumber, name, got, want.Get(name))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUploadLargeSendsContentMD5WithLockedParts(t *testing.T) {
+	server := &multipartServer{partHeaders: make(map[string]http.Header)}
+	client := newTestClient(t, server)
+
+	data := bytes.Repeat([]byte("a"), minUploadPartSize+1)
+	_, err := client.UploadLarge(context.Background(), "bucket", "key", bytes.NewReader(data), &MultipartOptions{
+		PartSize:      minUploadPartSize,
+		ObjectOptions: []ObjectOption{WithLegalHold(true)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for partNumber, part := range map[string][]byte{"1": data[:minUploadPartSize], "2": data[minUploadPartSize:]} {
+		want, _ := buildContentHash(part)
+		if got := server.partHeaders[partNumber].Get("Content-MD5"); got != want {
+			t.Errorf("part %s: got Content-MD5 %q, want %q", partNumber, got, want)
+		}
+	}
+}
+
+func TestPutObjectWithInfoSendsContentMD5WhenLocked(t *testing.T) {
+	var header http.Header
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+
+	data := []byte("hello")
+	retainUntil := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := client.PutObjectWithInfo(context.Background(), "bucket", "key", data, WithObjectLock("GOVERNANCE", retainUntil)); err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := buildContentHash(data); header.Get("Content-MD5") != want {
+		t.Errorf("got Content-MD5 %q, want %q", header.Get("Content-MD5"), want)
+	}
+
+	// a requested checksum satisfies the integrity check instead
+	if _, err := client.PutObjectWithInfo(context.Background(), "bucket", "key", data, WithObjectLock("GOVERNANCE", retainUntil), WithChecksum("SHA256", "")); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("Content-MD5"); got != "" {
+		t.Errorf("got Content-MD5 %q with a checksum", got)
+	}
+}
+
+func TestUploadSmallObjectWithChecksum(t *testing.T) {
+	tests := map[string]struct {
+		opts    MultipartOptions
+		trailer string
+	}{
+		"part checksum":        {MultipartOptions{ChecksumAlgorithm: "SHA256"}, "x-amz-checksum-sha256:" + checkInputChecksums["SHA256"]},
+		"full object checksum": {MultipartOptions{FullObjectChecksum: true}, "x-amz-checksum-crc64nvme:" + checkInputChecksums["CRC64NVME"]},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := &trailerRecorder{}
+			client := newTestClient(t, server)
+
+			if _, err := client.Upload(context.Background(), "bucket", "key", strings.NewReader("123456789"), 9, &test.opts); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(server.body, test.trailer+"\r\n\r\n") {
+				t.Errorf("body does not end with %q: %q", test.trailer, server.body)
+			}
+		})
+	}
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request sent with invalid options")
+	}))
+	for _, opts := range []MultipartOptions{
+		{ChecksumAlgorithm: "SHA256", FullObjectChecksum: true},
+		{PartSize: 1024},
+	} {
+		if _, err := client.Upload(context.Background(), "bucket", "key", strings.NewReader("123456789"), 9, &opts); err == nil {
+			t.Errorf("options %+v accepted", opts)
+		}
+	}
+}
+
+func TestUploadReturnsTransferStats(t *testing.T) {
+	t.Run("multipart", func(t *testing.T) {
+		client := newTestClient(t, &multipartServer{partHeaders: make(map[string]http.Header)})
+
+		size := int64(minUploadPartSize + 1)
+		result, err := client.UploadLarge(context.Background(), "bucket", "key", bytes.NewReader(make([]byte, size)), &MultipartOptions{PartSize: minUploadPartSize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats := result.Stats; stats.Bytes != size || stats.Size != size || stats.Parts != 2 || stats.Elapsed <= 0 {
+			t.Errorf("got stats %+v", stats)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		result, err := client.Upload(context.Background(), "bucket", "key", bytes.NewReader(nil), 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats := result.Stats; stats.Bytes != 0 || stats.Parts != 1 {
+			t.Errorf("got stats %+v, want 0 bytes in 1 part", stats)
+		}
+	})
+
+	t.Run("failed part", func(t *testing.T) {
+		client := newTestClient(t, &multipartServer{partHeaders: make(map[string]http.Header), failPart: "2"})
+
+		data := make([]byte, 2*minUploadPartSize)
+		result, err := client.UploadLarge(context.Background(), "bucket", "key", bytes.NewReader(data), &MultipartOptions{PartSize: minUploadPartSize, Concurrency: 1})
+		if err == nil {
+			t.Fatal("got no error")
+		}
+		if stats := result.Stats; stats.Bytes != minUploadPartSize || stats.Parts != 1 {
+			t.Errorf("got stats %+v, want the first part", stats)
+		}
+	})
+
+	t.Run("failed request", func(t *testing.T) {
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "", http.StatusForbidden)
+		}))
+
+		result, err := client.Upload(context.Background(), "bucket", "key", strings.NewReader("hello"), 5, nil)
+		if err == nil {
+			t.Fatal("got no error")
+		}
+		if stats := result.Stats; stats.Size != 5 || stats.Parts != 0 || stats.Elapsed <= 0 {
+			t.Errorf("got stats %+v", stats)
+		}
+	})
+}
+
+func TestUploadLargeRampsUpToMaxConcurrency(t *testing.T) {
+	server := &multipartServer{partHeaders: make(map[string]http.Header)}
+	var (
+		mu             sync.Mutex
+		inFlight, peak int
+	)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("partNumber") {
+			mu.Lock()
+			inFlight++
+			peak = max(peak, inFlight)
+			mu.Unlock()
+			time.Sleep(50 * time.Millisecond)
+			defer func() {
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			}()
+		}
+		server.ServeHTTP(w, r)
+	}))
+
+	data := make([]byte, 10*minUploadPartSize)
+	_, err := client.UploadLarge(context.Background(), "bucket", "key", bytes.NewReader(data), &MultipartOptions{
+		PartSize:       minUploadPartSize,
+		Concurrency:    1,
+		MaxConcurrency: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak != 3 {
+		t.Errorf("got %d parts in flight at most, want 3", peak)
+	}
+}
+
+func TestUploadLargeCompletesPartsInOrder(t *testing.T) {
+	server := &multipartServer{partHeaders: make(map[string]http.Header)}
+	client := newTestClient(t, server)
+
+	data := make([]byte, 2*minUploadPartSize+1)
+	result, err := client.UploadLarge(context.Background(), "bucket", "key", bytes.NewReader(data), &MultipartOptions{
+		PartSize:    minUploadPartSize,
+		Concurrency: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ETag != "etag-2" {
+		t.Errorf("got ETag %q, want etag-2", result.ETag)
+	}
+	var completion struct {
+		Parts []CompletedPart `xml:"Part"`
+	}
+	if err := xml.Unmarshal([]byte(server.completion), &completion); err != nil {
+		t.Fatal(err)
+	}
+	for i, part := range completion.Parts {
+		if want := fmt.Sprintf(`"etag-%d"`, i+1); part.PartNumber != i+1 || part.ETag != want {
+			t.Errorf("got part %d with ETag %s at position %d, want %s", part.PartNumber, part.ETag, i+1, want)
+		}
+	}
+	if len(completion.Parts) != 3 {
+		t.Errorf("got %d parts, want 3", len(completion.Parts))
+	}
+	if server.aborted {
+		t.Error("completed upload was aborted")
+	}
+}
+
+func TestUploadLargeAbortsOnPartFailure(t *testing.T) {
+	server := &multipartServer{partHeaders: make(map[string]http.Header), failPart: "2"}
+	client := newTestClient(t, server)
+
+	data := make([]byte, 2*minUploadPartSize)
+	_, err := client.UploadLarge(context.Background(), "bucket", "key", bytes.NewReader(data), &MultipartOptions{PartSize: minUploadPartSize})
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	if !server.aborted {
+		t.Error("failed upload was not aborted")
+	}
+	if server.completion != "" {
+		t.Error("failed upload was completed")
+	}
+}
+
+func TestCreateMultipartUploadAbortsUploadOfLostResponse(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		creates int
+		aborted []string
+	)
+	earlier := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	uploads := []MultipartUpload{
+		{Key: "key", UploadId: "other-writer", Initiated: earlier},
+		{Key: "key-2", UploadId: "other-key", Initiated: time.Now().UTC().Format(time.RFC3339)},
+	}
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			creates++
+			uploadId := fmt.Sprintf("upload-%d", creates)
+			uploads = append(uploads, MultipartUpload{Key: "key", UploadId: uploadId, Initiated: time.Now().UTC().Format(time.RFC3339)})
+			if creates == 1 {
+				// the upload is created, but the response is lost
+				http.Error(w, "", http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>key</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, uploadId)
+		case r.Method == http.MethodGet && query.Has("uploads"):
+			data, _ := xml.Marshal(ListMultipartUploadsResult{Bucket: "bucket", Uploads: uploads})
+			w.Write(data)
+		case r.Method == http.MethodDelete:
+			aborted = append(aborted, query.Get("uploadId"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	client.config.RetryBaseDelay = time.Millisecond
+
+	upload, err := client.createMultipartUpload(context.Background(), "bucket", "key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upload.UploadId != "upload-2" {
+		t.Errorf("got upload %s, want upload-2", upload.UploadId)
+	}
+	if want := []string{"upload-1"}; !slices.Equal(aborted, want) {
+		t.Errorf("got aborted uploads %v, want %v", aborted, want)
+	}
+}
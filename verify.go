@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// checksum algorithms in the order they are preferred for verification
+var verifyAlgorithms = []string{"CRC64NVME", "CRC32C", "CRC32", "SHA256", "SHA1"}
+
+// GetObjectVerified fetches an object and verifies the received bytes against
+// a stored full-object checksum or, without one, against the MD5 ETag.
+// Composite and multipart values that are not a digest of the whole object
+// are skipped, so the content is returned unverified if nothing applies.
+// WithTransparentDecompression is ignored: the stored checksums describe the
+// encoded bytes, so gzip-encoded objects are returned as stored.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html
+func (c *Client) GetObjectVerified(ctx context.Context, bucketName, objectName string, opts ...ObjectOption) ([]byte, error) {
+	options := newObjectOptions(opts)
+	// verify the bytes as stored, not as decompressed by the transport
+	options.decompress = false
+	req, err := c.newRequest(ctx, http.MethodGet, bucketName, objectName, options.query(), nil)
+	if err != nil {
+		return nil, err
+	}
+	options.setHeaders(req)
+	req.Header.Set("x-amz-checksum-mode", "ENABLED")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	if err := verifyObject(resp.Header, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// verifyObject compares data with the checksum or ETag headers of its response.
+func verifyObject(header http.Header, data []byte) error {
+	for _, algorithm := range verifyAlgorithms {
+		expected := header.Get("x-amz-checksum-" + strings.ToLower(algorithm))
+		// composite checksums end in -<number of parts>
+		if expected == "" || strings.Contains(expected, "-") {
+			continue
+		}
+		actual, err := ComputeChecksum(algorithm, data)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return fmt.Errorf("checksum mismatch: expected %s %s, got %s", algorithm, expected, actual)
+		}
+		return nil
+	}
+
+	etag := trimETag(header.Get("ETag"))
+	// multipart ETags end in -<number of parts>, SSE-KMS and SSE-C ETags are no MD5 of the content
+	if etag == "" || strings.Contains(etag, "-") || header.Get("x-amz-server-side-encryption") == "aws:kms" ||
+		header.Get("x-amz-server-side-encryption-customer-algorithm") != "" {
+		return nil
+	}
+	hash := md5.Sum(data)
+	if actual := hex.EncodeToString(hash[:]); actual != etag {
+		return fmt.Errorf("checksum mismatch: expected MD5 %s, got %s", etag, actual)
+	}
+	return nil
+}
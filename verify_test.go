@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"testing"
+)
+
+func TestVerifyObject(t *testing.T) {
+	const md5ETag = `"25f9e794323b453885f5181f1b624d0b"`
+	tests := []struct {
+		name    string
+		header  map[string]string
+		wantErr bool
+	}{
+		{name: "checksum", header: map[string]string{"x-amz-checksum-crc32": "y/Q5Jg==", "ETag": `"other"`}},
+		{name: "checksum mismatch", header: map[string]string{"x-amz-checksum-crc32": "AAAAAA=="}, wantErr: true},
+		{name: "composite checksum", header: map[string]string{"x-amz-checksum-crc32": "AAAAAA==-2", "ETag": md5ETag}},
+		{name: "MD5 ETag", header: map[string]string{"ETag": md5ETag}},
+		{name: "MD5 ETag mismatch", header: map[string]string{"ETag": `"00000000000000000000000000000000"`}, wantErr: true},
+		{name: "multipart ETag", header: map[string]string{"ETag": `"00000000000000000000000000000000-2"`}},
+		{name: "SSE-KMS ETag", header: map[string]string{"ETag": `"0000"`, "x-amz-server-side-encryption": "aws:kms"}},
+		{name: "SSE-C ETag", header: map[string]string{"ETag": `"0000"`, "x-amz-server-side-encryption-customer-algorithm": "AES256"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for name, value := range tt.header {
+				header.Set(name, value)
+			}
+			err := verifyObject(header, []byte("123456789"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetObjectVerifiedRejectsCorruptContent(t *testing.T) {
+	var checksumMode string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checksumMode = r.Header.Get("x-amz-checksum-mode")
+		w.Header().Set("x-amz-checksum-crc32", "y/Q5Jg==")
+		w.Write([]byte("123456780"))
+	}))
+
+	if _, err := client.GetObjectVerified(context.Background(), "bucket", "key"); err == nil {
+		t.Error("got no error for corrupt content")
+	}
+	if checksumMode != "ENABLED" {
+		t.Errorf("got x-amz-checksum-mode %q, want ENABLED", checksumMode)
+	}
+}
+
+func TestGetObjectVerifiedIgnoresTransparentDecompression(t *testing.T) {
+	var stored bytes.Buffer
+	gz := gzip.NewWriter(&stored)
+	gz.Write([]byte("123456789"))
+	gz.Close()
+	digest := md5.Sum(stored.Bytes())
+
+	var acceptEncoding string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("ETag", `"`+hex.EncodeToString(digest[:])+`"`)
+		w.Write(stored.Bytes())
+	}))
+
+	data, err := client.GetObjectVerified(context.Background(), "bucket", "key", WithTransparentDecompression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acceptEncoding != "identity" {
+		t.Errorf("got Accept-Encoding %q, want identity", acceptEncoding)
+	}
+	if !bytes.Equal(data, stored.Bytes()) {
+		t.Errorf("got %q, want the stored gzip bytes", data)
+	}
+}
@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// default interval between two polls of a waiter
+const defaultPollInterval = 5 * time.Second
+
+// WaitUntilObjectExists polls the object with HEAD requests until it exists
+// or the context is done.
+func (c *Client) WaitUntilObjectExists(ctx context.Context, bucketName, objectName string, pollInterval time.Duration) error {
+	return c.waitForObject(ctx, bucketName, objectName, pollInterval, true)
+}
+
+// WaitUntilObjectNotExists polls the object with HEAD requests until it is gone
+// or the context is done.
+func (c *Client) WaitUntilObjectNotExists(ctx context.Context, bucketName, objectName string, pollInterval time.Duration) error {
+	return c.waitForObject(ctx, bucketName, objectName, pollInterval, false)
+}
+
+// waitForObject polls until the existence of the object matches exists.
+// Errors other than a missing object end the wait immediately.
+func (c *Client) waitForObject(ctx context.Context, bucketName, objectName string, pollInterval time.Duration, exists bool) error {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := c.HeadObject(ctx, bucketName, objectName)
+		if err == nil {
+			resp.Body.Close()
+			if exists {
+				return nil
+			}
+		} else if !isNotFound(err) {
+			return err
+		} else if !exists {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// isNotFound reports whether err is a 404 response.
+func isNotFound(err error) bool {
+	var errorResponse ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.StatusCode == http.StatusNotFound
+}